@@ -1,6 +1,8 @@
 package automigrate
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/gogf/gf/database/gdb"
@@ -17,16 +19,128 @@ type DB struct {
 	dialect   Dialect
 	search    *search
 	values    sync.Map
+	catalog   *CatalogCache
 }
 
 func NewDB(name string, db gdb.DB) *DB {
-	return &DB{db: db, dialect: newDialect(name, db)}
+	catalog := &CatalogCache{}
+	return &DB{db: db, dialect: newDialect(name, db, catalog), catalog: catalog}
 }
 
-func (s *DB) AutoMigrate(values ...interface{}) *DB {
-	db := s.Unscoped()
-	for _, value := range values {
-		db = db.NewScope(value).autoMigrate().db
+// Open create a new DB, the dialect is inferred from the type of the database configured for db,
+// returns an error if no dialect is registered for that type
+func Open(db gdb.DB) (*DB, error) {
+	dbType := gdbType(db)
+	name, ok := gdbDialectNames[dbType]
+	if !ok {
+		name = dbType
+	}
+
+	if _, ok := GetDialect(name); !ok {
+		return nil, fmt.Errorf("%w: database type `%v`, is the dialect package imported?", ErrUnsupportedDialect, dbType)
+	}
+	return NewDB(name, db), nil
+}
+
+func (s *DB) AutoMigrate(values ...interface{}) (db *DB) {
+	_, held := s.Get("automigrate:lock_held")
+	db, unlock, err := s.Unscoped().lock()
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			db.AddError(err)
+		}
+		// the lock taken here is released, migrating through the returned DB takes it again
+		if !held {
+			db.values.Delete("automigrate:lock_held")
+		}
+	}()
+
+	switch mode, _ := db.Get("automigrate:transaction"); mode {
+	case "batch":
+		return db.transaction(func(db *DB) *DB {
+			return db.autoMigrate(values, false)
+		})
+	case "model", true:
+		return db.autoMigrate(values, true)
+	}
+	return db.autoMigrate(values, false)
+}
+
+// migrateOwnTable migrate a table automigrate keeps its own state in, like the lock or the migrations history, or a join table it creates,
+// leaving out the settings that would prune, parallelize, wrap in a transaction or lock the migration of the application's models
+func (s *DB) migrateOwnTable(value interface{}) error {
+	db := s.clone()
+	db.Error = nil
+	for _, name := range []string{"automigrate:prune", "automigrate:workers", "automigrate:transaction", "automigrate:lock"} {
+		db.values.Delete(name)
+	}
+	return db.AutoMigrate(value).Error
+}
+
+// autoMigrate migrate the models, each one in a transaction of its own if perModel is true
+func (s *DB) autoMigrate(values []interface{}, perModel bool) *DB {
+	db := s.withSnapshots()
+	run := func(fn func(*DB) *DB) {
+		db = db.run(fn, perModel)
+	}
+
+	order := db.orderModels(values)
+	order.warnCycles()
+	migrate := func(i int) func(*DB) *DB {
+		return func(db *DB) *DB {
+			db = db.NewScope(order.values[i]).autoMigrate().db
+			return db.addForeignKeys(order.foreignKeys[i])
+		}
+	}
+	// models are migrated at once on connections of their own, unless running in a transaction
+	if workers := db.workers(); workers > 1 && !inTransaction(db.db) {
+		db = db.migrateParallel(order, workers, func(db *DB, i int) *DB {
+			return db.run(migrate(i), perModel)
+		})
+	} else {
+		for i := range order.values {
+			run(migrate(i))
+		}
+	}
+	// foreign keys between models depending on each other are added once all tables exist
+	if foreignKeys := order.foreignKeys[len(order.values)]; len(foreignKeys) > 0 {
+		run(func(db *DB) *DB {
+			return db.addForeignKeys(foreignKeys)
+		})
+	}
+	if prune, ok := db.Get("automigrate:prune"); ok && prune == true {
+		run(func(db *DB) *DB {
+			return db.prune(values...)
+		})
+	}
+	// introspection queries the context interrupted don't report it
+	if err := contextOf(s.db).Err(); err != nil && !errors.Is(db.Error, err) {
+		db.AddError(err)
+	}
+	return db.withoutSnapshots()
+}
+
+// run run fn, in a transaction of its own if perModel is true, unless the context interrupted the migration already
+func (s *DB) run(fn func(*DB) *DB, perModel bool) *DB {
+	// an interrupted migration stops at the statement its context interrupted
+	if contextOf(s.db).Err() != nil {
+		return s
+	}
+	if perModel {
+		return s.transaction(fn)
+	}
+	return fn(s)
+}
+
+// addForeignKeys create the foreign keys of the relationship fields
+func (s *DB) addForeignKeys(fields []modelField) *DB {
+	db := s
+	for _, field := range fields {
+		db = db.NewScope(field.value).autoForeignKey(field.field).db
 	}
 	return db
 }
@@ -88,8 +202,9 @@ func (s *DB) clone() *DB {
 	db := &DB{
 		db:      s.db,
 		Error:   s.Error,
-		dialect: newDialect(s.dialect.GetName(), s.db),
+		dialect: newDialect(s.dialect.GetName(), s.db, s.catalog),
 		Value:   s.Value,
+		catalog: s.catalog,
 	}
 
 	s.values.Range(func(k, v interface{}) bool {
@@ -147,3 +262,42 @@ func (s *DB) AddUniqueIndex(indexName string, columns ...string) *DB {
 	scope.addIndex(true, indexName, columns...)
 	return scope.db
 }
+
+// AddForeignKey add foreign key to the given scope, e.g:
+//     db.Model(&User{}).AddForeignKey("city_id", "cities(id)", "RESTRICT", "RESTRICT")
+func (s *DB) AddForeignKey(field string, dest string, onDelete string, onUpdate string) *DB {
+	scope := s.NewScope(s.Value)
+	scope.addForeignKey(field, dest, onDelete, onUpdate)
+	return scope.db
+}
+
+// DropColumn drop the column of the model's table
+func (s *DB) DropColumn(column string) *DB {
+	scope := s.Unscoped().NewScope(s.Value)
+	scope.dropColumn(column, "drop requested")
+	return scope.db
+}
+
+// DropIndex drop the index of the model's table
+func (s *DB) DropIndex(indexName string) *DB {
+	scope := s.Unscoped().NewScope(s.Value)
+	scope.dropIndex(indexName, "drop requested")
+	return scope.db
+}
+
+// DropTable drop the tables of the models, or the tables named by the string values
+func (s *DB) DropTable(values ...interface{}) *DB {
+	db := s.clone()
+	for _, value := range values {
+		// each value gets a scope of its own, so that a table name doesn't apply to the values after it
+		from := s.clone()
+		from.Error = nil
+		if tableName, ok := value.(string); ok {
+			from = from.Table(tableName)
+		}
+		scope := from.NewScope(value)
+		scope.dropTable("drop requested")
+		db.AddError(scope.db.Error)
+	}
+	return db
+}
@@ -0,0 +1,85 @@
+package automigrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// checkNameRegexp match the name a CHECK tag may start with, as in check:chk_price,price >= 0
+var checkNameRegexp = regexp.MustCompile(`^\s*(\w+)\s*,(.*)$`)
+
+// checkConstraint is a check constraint declared by the CHECK tag of a field
+type checkConstraint struct {
+	name       string
+	expression string
+}
+
+// modelChecks return the check constraints declared by the CHECK tags, the unnamed ones being named with BuildKeyName
+func (scope *Scope) modelChecks() (checks []checkConstraint) {
+	for _, field := range scope.GetModelStruct().StructFields {
+		value, ok := field.TagSettingsGet("CHECK")
+		if !ok || !field.IsNormal {
+			continue
+		}
+
+		check := checkConstraint{name: scope.Dialect().BuildKeyName("chk", scope.TableName(), field.DBName), expression: strings.TrimSpace(value)}
+		if submatch := checkNameRegexp.FindStringSubmatch(value); submatch != nil {
+			check.name, check.expression = submatch[1], strings.TrimSpace(submatch[2])
+		}
+		checks = append(checks, check)
+	}
+	return
+}
+
+// autoCheck add the check constraints missing from the table, and replace the ones whose expression changed
+func (scope *Scope) autoCheck() *Scope {
+	checks := scope.modelChecks()
+	if len(checks) == 0 {
+		return scope
+	}
+
+	expressions := scope.existingChecks()
+	if expressions == nil {
+		return scope
+	}
+
+	for _, check := range checks {
+		reason := "check missing in table"
+		if expression, ok := expressions[strings.ToLower(check.name)]; ok {
+			if normalizeCondition(expression) == normalizeCondition(check.expression) {
+				continue
+			}
+			reason = fmt.Sprintf("check changed from %q to %q", expression, check.expression)
+			scope.describe(scope.TableName(), "drop_check", check.name, reason)
+			scope.Raw(scope.dropCheckSQL(scope.TableName(), check.name)).Exec()
+		}
+		scope.describe(scope.TableName(), "add_check", check.name, reason)
+		scope.Raw(fmt.Sprintf("ALTER TABLE %v ADD CONSTRAINT %v CHECK (%v)", scope.QuotedTableName(), scope.quoteIfPossible(check.name), check.expression)).Exec()
+	}
+	return scope
+}
+
+// existingChecks return the expressions of the check constraints of the table by lower cased name, from its snapshot or else from the catalog,
+// nil if the dialect can't read them
+func (scope *Scope) existingChecks() map[string]string {
+	if snapshot := scope.tableSnapshot(scope.TableName()); snapshot != nil {
+		return snapshot.checks
+	}
+
+	dialect, ok := scope.Dialect().(checkDialect)
+	if !ok {
+		return nil
+	}
+
+	existing, err := dialect.Checks(scope.TableName())
+	if scope.Err(err) != nil || existing == nil {
+		return nil
+	}
+
+	expressions := map[string]string{}
+	for name, expression := range existing {
+		expressions[strings.ToLower(name)] = expression
+	}
+	return expressions
+}
@@ -0,0 +1,319 @@
+package automigrate
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ColumnInfo is the definition of an existing column, read from the database catalog
+type ColumnInfo struct {
+	Name     string
+	Type     string // type of the column, spelled like DataTypeOf, e.g. varchar(255)
+	Nullable bool
+	Default  sql.NullString // default value expression of the column, if any
+}
+
+// unlimited is the capacity of types without a length limit, like text or nvarchar(max)
+const unlimited = 1 << 62
+
+var (
+	// dataTypeClauseRegexp match the first clause following the type in a column definition
+	dataTypeClauseRegexp = regexp.MustCompile(`\b(not|null|unique|default|comment|auto_increment|autoincrement|identity|primary|generated|collate|charset|character set|check|references|constraint)\b`)
+	dataTypeArgsRegexp   = regexp.MustCompile(`\(([^)]*)\)`)
+	// defaultCastRegexp match the cast postgres adds to default values, as in 'abc'::character varying
+	defaultCastRegexp = regexp.MustCompile(`::[\w\s"]+$`)
+	// conditionNoiseRegexp match what catalogs add to the conditions of partial indexes and checks, like quotes, parentheses, table qualifiers and casts
+	conditionNoiseRegexp = regexp.MustCompile("[\\s\"`\\[\\]()]+|\\b[a-z_][\\w$]*\\.|^(where|check)\\b|::[a-z_]\\w*(\\s+varying)?")
+	// indexColumnLengthRegexp match the prefix length of a mysql index column, as in name(10)
+	indexColumnLengthRegexp = regexp.MustCompile(`\(\d+\)$`)
+)
+
+// dataTypeAliases maps the alternative spellings of types onto one name, so the catalogs' and DataTypeOf's spellings compare equal
+var dataTypeAliases = map[string]string{
+	"integer":                     "int",
+	"int4":                        "int",
+	"serial":                      "int",
+	"serial4":                     "int",
+	"int2":                        "smallint",
+	"smallserial":                 "smallint",
+	"int8":                        "bigint",
+	"bigserial":                   "bigint",
+	"serial8":                     "bigint",
+	"bool":                        "boolean",
+	"float8":                      "double",
+	"double precision":            "double",
+	"float4":                      "real",
+	"decimal":                     "numeric",
+	"character varying":           "varchar",
+	"character":                   "char",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+}
+
+// dataTypeFamilies groups the types converting into each other, ranked by the values they hold.
+// The types with a zero rank hold as many values as their arguments allow.
+var dataTypeFamilies = map[string]struct {
+	family string
+	rank   int64
+}{
+	"tinyint":            {"integer", 1},
+	"smallint":           {"integer", 2},
+	"mediumint":          {"integer", 3},
+	"int":                {"integer", 4},
+	"bigint":             {"integer", 5},
+	"tinyint unsigned":   {"unsigned", 1},
+	"smallint unsigned":  {"unsigned", 2},
+	"mediumint unsigned": {"unsigned", 3},
+	"int unsigned":       {"unsigned", 4},
+	"bigint unsigned":    {"unsigned", 5},
+	"real":               {"float", 1},
+	"float":              {"float", 2},
+	"double":             {"float", 2},
+	"numeric":            {"decimal", 0},
+	"number":             {"decimal", 0},
+	"char":               {"string", 0},
+	"varchar":            {"string", 0},
+	"varchar2":           {"string", 0},
+	"tinytext":           {"string", 255},
+	"text":               {"string", unlimited},
+	"mediumtext":         {"string", unlimited},
+	"longtext":           {"string", unlimited},
+	"clob":               {"string", unlimited},
+	"nchar":              {"unicode", 0},
+	"nvarchar":           {"unicode", 0},
+	"nvarchar2":          {"unicode", 0},
+	"ntext":              {"unicode", unlimited},
+	"nclob":              {"unicode", unlimited},
+	"binary":             {"binary", 0},
+	"varbinary":          {"binary", 0},
+	"raw":                {"binary", 0},
+	"tinyblob":           {"binary", 255},
+	"blob":               {"binary", unlimited},
+	"mediumblob":         {"binary", unlimited},
+	"longblob":           {"binary", unlimited},
+	"bytea":              {"binary", unlimited},
+	"image":              {"binary", unlimited},
+	"date":               {"time", 1},
+	"smalldatetime":      {"time", 2},
+	"datetime":           {"time", 3},
+	"timestamp":          {"time", 3},
+	"datetime2":          {"time", 4},
+	"timestamptz":        {"time", 5},
+	"datetimeoffset":     {"time", 5},
+}
+
+// dataType is a sql type split into its lower cased name and its arguments, like varchar and 255
+type dataType struct {
+	name string
+	args []string
+	sql  string // the type as written, without the clauses following it
+}
+
+// parseDataType return the type of a column definition, leaving out its constraints, default, comment...
+func parseDataType(definition string) dataType {
+	var (
+		written = strings.TrimSpace(definition)
+		lower   = strings.ToLower(written)
+		masked  = lower
+		args    []string
+	)
+
+	// the arguments are masked so the clauses are not looked for in them, as in enum('not', 'null')
+	loc := dataTypeArgsRegexp.FindStringSubmatchIndex(lower)
+	if loc != nil {
+		masked = lower[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + lower[loc[1]:]
+	}
+	if clause := dataTypeClauseRegexp.FindStringIndex(masked); clause != nil {
+		written, lower, masked = written[:clause[0]], lower[:clause[0]], masked[:clause[0]]
+	}
+	// arguments found after the first clause belong to it, as in int IDENTITY(1,1)
+	if loc != nil && loc[1] <= len(lower) {
+		for _, arg := range strings.Split(lower[loc[2]:loc[3]], ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+		lower = masked
+	}
+
+	name := strings.Join(strings.Fields(lower), " ")
+	if alias, ok := dataTypeAliases[name]; ok {
+		name = alias
+	} else if words := strings.SplitN(name, " ", 2); len(words) == 2 {
+		if alias, ok := dataTypeAliases[words[0]]; ok {
+			name = alias + " " + words[1]
+		}
+	}
+	return dataType{name: name, args: args, sql: strings.TrimSpace(written)}
+}
+
+func (t dataType) String() string {
+	if len(t.args) == 0 {
+		return t.name
+	}
+	return t.name + "(" + strings.Join(t.args, ",") + ")"
+}
+
+// equal check the types are the same, the display width of integers is ignored
+func (t dataType) equal(other dataType) bool {
+	if t.name != other.name {
+		return false
+	}
+	if family := dataTypeFamilies[t.name].family; family == "integer" || family == "unsigned" {
+		return true
+	}
+	return t.String() == other.String()
+}
+
+// canWiden check a column of the type can be changed to type `to` without losing data
+func (t dataType) canWiden(to dataType) bool {
+	from, ok := dataTypeFamilies[t.name]
+	if !ok {
+		return false
+	}
+	into, ok := dataTypeFamilies[to.name]
+	if !ok {
+		return false
+	}
+
+	switch {
+	case from.family == into.family:
+	case from.family == "string" && into.family == "unicode":
+	case from.family == "unsigned" && into.family == "integer":
+		// a signed integer holds the unsigned values of the rank below it, as bigint does those of int unsigned
+		return into.rank > from.rank
+	default:
+		return false
+	}
+
+	switch from.family {
+	case "integer", "unsigned", "float":
+		return into.rank >= from.rank
+	case "decimal":
+		fromPrecision, fromScale := t.precisionAndScale()
+		toPrecision, toScale := to.precisionAndScale()
+		return toPrecision == unlimited || fromPrecision != unlimited && toPrecision-toScale >= fromPrecision-fromScale && toScale >= fromScale
+	case "time":
+		if t.name != to.name {
+			return into.rank > from.rank
+		}
+		return len(t.args) == 0 && len(to.args) == 0 || len(t.args) > 0 && len(to.args) > 0 && atoi(to.args[0]) >= atoi(t.args[0])
+	}
+	return to.capacity(into.rank) >= t.capacity(from.rank)
+}
+
+// capacity return the length of the string or binary type
+func (t dataType) capacity(rank int64) int64 {
+	if rank != 0 {
+		return rank
+	}
+	if len(t.args) == 0 {
+		if t.name == "char" || t.name == "nchar" || t.name == "binary" {
+			return 1
+		}
+		return unlimited
+	}
+	if t.args[0] == "max" {
+		return unlimited
+	}
+	return atoi(t.args[0])
+}
+
+// precisionAndScale return the digits of the decimal type, and how many of them follow the decimal point
+func (t dataType) precisionAndScale() (precision, scale int64) {
+	if len(t.args) == 0 || t.args[0] == "*" {
+		return unlimited, 0
+	}
+	if len(t.args) > 1 {
+		scale = atoi(t.args[1])
+	}
+	return atoi(t.args[0]), scale
+}
+
+func atoi(str string) int64 {
+	i, _ := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+	return i
+}
+
+// equalDefault check the default values are the same, once the casts, parentheses and quotes catalogs wrap them in are removed
+func equalDefault(value, other string) bool {
+	return strings.EqualFold(normalizeDefault(value), normalizeDefault(other))
+}
+
+func normalizeDefault(value string) string {
+	for {
+		normalized := strings.TrimSpace(defaultCastRegexp.ReplaceAllString(strings.TrimSpace(value), ""))
+		if isWrapped(normalized, '(', ')') {
+			normalized = normalized[1 : len(normalized)-1]
+		}
+		if normalized == value {
+			break
+		}
+		value = normalized
+	}
+
+	if len(value) > 2 && (value[0] == 'N' || value[0] == 'n') && value[1] == '\'' {
+		value = value[1:]
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = strings.Replace(value[1:len(value)-1], "''", "'", -1)
+	}
+
+	switch strings.ToLower(value) {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return value
+}
+
+// isWrapped check the whole string is enclosed by the open and close characters, as (a) but unlike (a) + (b)
+func isWrapped(str string, open, close byte) bool {
+	if len(str) < 2 || str[0] != open || str[len(str)-1] != close {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(str)-1; i++ {
+		switch str[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+		if depth == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// equalIndexColumns check the indexes are on the same columns, in the same order
+func equalIndexColumns(columns, other []string) bool {
+	if len(columns) != len(other) {
+		return false
+	}
+	for i := range columns {
+		if !strings.EqualFold(normalizeIndexColumn(columns[i]), normalizeIndexColumn(other[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeIndexColumn(column string) string {
+	return strings.Trim(indexColumnLengthRegexp.ReplaceAllString(strings.TrimSpace(column), ""), "\"`[]")
+}
+
+// normalizeCondition return the condition of a partial index or a check stripped of its where or check keyword, quotes,
+// parentheses, spaces, table qualifiers and casts, as catalogs spell it differently from the statement creating it
+func normalizeCondition(condition string) string {
+	condition = strings.ToLower(strings.TrimSpace(condition))
+	for {
+		normalized := conditionNoiseRegexp.ReplaceAllString(condition, "")
+		if normalized == condition {
+			return condition
+		}
+		condition = normalized
+	}
+}
@@ -0,0 +1,160 @@
+package automigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDataType(t *testing.T) {
+	tests := []struct {
+		definition string
+		name       string
+		args       []string
+		sql        string
+	}{
+		{"varchar(255)", "varchar", []string{"255"}, "varchar(255)"},
+		{"character varying(255)", "varchar", []string{"255"}, "character varying(255)"},
+		{"VARCHAR(255) NOT NULL DEFAULT 'x'", "varchar", []string{"255"}, "VARCHAR(255)"},
+		{"int IDENTITY(1,1)", "int", nil, "int"},
+		{"integer primary key autoincrement", "int", nil, "integer"},
+		{"int unsigned AUTO_INCREMENT", "int unsigned", nil, "int unsigned"},
+		{"int(10) unsigned", "int unsigned", []string{"10"}, "int(10) unsigned"},
+		{"int8", "bigint", nil, "int8"},
+		{"decimal(10, 2)", "numeric", []string{"10", "2"}, "decimal(10, 2)"},
+		{"timestamp with time zone", "timestamptz", nil, "timestamp with time zone"},
+		{"double precision", "double", nil, "double precision"},
+		{"nvarchar(max) NULL", "nvarchar", []string{"max"}, "nvarchar(max)"},
+		{"enum('not','null') NOT NULL", "enum", []string{"'not'", "'null'"}, "enum('not','null')"},
+		{"text COMMENT 'notes'", "text", nil, "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.definition, func(t *testing.T) {
+			got := parseDataType(tt.definition)
+			if got.name != tt.name || !reflect.DeepEqual(got.args, tt.args) || got.sql != tt.sql {
+				t.Errorf("parseDataType(%q) = %q %q %q, want %q %q %q", tt.definition, got.name, got.args, got.sql, tt.name, tt.args, tt.sql)
+			}
+		})
+	}
+}
+
+func TestDataTypeEqual(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"character varying(255)", "varchar(255)", true},
+		{"varchar(255)", "varchar(100)", false},
+		{"int(11)", "int", true},
+		{"integer", "int IDENTITY(1,1)", true},
+		{"int unsigned", "int", false},
+		{"numeric(10,2)", "decimal(10, 2)", true},
+		{"timestamp without time zone", "timestamp", true},
+		{"timestamp", "timestamptz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" "+tt.to, func(t *testing.T) {
+			if got := parseDataType(tt.from).equal(parseDataType(tt.to)); got != tt.want {
+				t.Errorf("%q equal %q = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanWiden(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"varchar(100)", "varchar(200)", true},
+		{"varchar(200)", "varchar(100)", false},
+		{"character varying(255)", "text", true},
+		{"text", "varchar(255)", false},
+		{"varchar(10)", "nvarchar(10)", true},
+		{"nvarchar(10)", "varchar(10)", false},
+		{"nvarchar(4000)", "nvarchar(max)", true},
+		{"char", "char(10)", true},
+		{"tinytext", "text", true},
+		{"varbinary(16)", "blob", true},
+		{"smallint", "int", true},
+		{"int", "bigint", true},
+		{"bigint", "int", false},
+		{"int unsigned", "bigint", true},
+		{"int unsigned", "int", false},
+		{"smallint unsigned", "int", true},
+		{"int", "int unsigned", false},
+		{"int unsigned", "bigint unsigned", true},
+		{"bigint unsigned", "bigint", false},
+		{"real", "double precision", true},
+		{"double", "real", false},
+		{"numeric(10,2)", "numeric(12,2)", true},
+		{"numeric(10,2)", "numeric(10,4)", false},
+		{"numeric(10,2)", "numeric(12,4)", true},
+		{"numeric(10,2)", "numeric", true},
+		{"numeric", "numeric(38,2)", false},
+		{"date", "datetime", true},
+		{"datetime", "date", false},
+		{"datetime2(3)", "datetime2(7)", true},
+		{"datetime2(7)", "datetime2(3)", false},
+		{"timestamp", "timestamp with time zone", true},
+		{"int", "varchar(20)", false},
+		{"geometry", "geometry", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" "+tt.to, func(t *testing.T) {
+			if got := parseDataType(tt.from).canWiden(parseDataType(tt.to)); got != tt.want {
+				t.Errorf("%q canWiden %q = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqualDefault(t *testing.T) {
+	tests := []struct {
+		value, other string
+		want         bool
+	}{
+		{"((0))", "0", true},
+		{"('x')", "'x'", true},
+		{"'x'::character varying", "x", true},
+		{"'2020-01-01 00:00:00'::timestamp without time zone", "2020-01-01 00:00:00", true},
+		{"N'abc'", "'abc'", true},
+		{"'it''s'", "it's", true},
+		{"true", "1", true},
+		{"FALSE", "0", true},
+		{"CURRENT_TIMESTAMP", "current_timestamp", true},
+		{"(1) + (2)", "1 + 2", false},
+		{"0", "1", false},
+		{"'x'", "'y'", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.other, func(t *testing.T) {
+			if got := equalDefault(tt.value, tt.other); got != tt.want {
+				t.Errorf("equalDefault(%q, %q) = %v, want %v", tt.value, tt.other, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCondition(t *testing.T) {
+	tests := []struct {
+		condition, other string
+		want             bool
+	}{
+		{"CHECK ((price >= (0)::numeric))", "price >= 0", true},
+		{"(`price` >= 0)", "price >= 0", true},
+		{"([price]>=(0))", "price >= 0", true},
+		{"\"products\".\"price\" >= 0", "price >= 0", true},
+		{"WHERE (deleted_at IS NULL)", "deleted_at IS NULL", true},
+		{"((status)::text = 'active'::character varying)", "status = 'active'", true},
+		{"price >= 0", "price > 0", false},
+		{"deleted_at IS NULL", "deleted_at IS NOT NULL", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			if got := normalizeCondition(tt.condition) == normalizeCondition(tt.other); got != tt.want {
+				t.Errorf("normalizeCondition(%q) = %q, normalizeCondition(%q) = %q, equal %v, want %v",
+					tt.condition, normalizeCondition(tt.condition), tt.other, normalizeCondition(tt.other), got, tt.want)
+			}
+		})
+	}
+}
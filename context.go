@@ -0,0 +1,62 @@
+package automigrate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gogf/gf/database/gdb"
+)
+
+// contextLink is a connection pool, connection or transaction able to run statements with a context
+type contextLink interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// ctxLink lets gdb run statements with the context, which interrupts them when canceled or past its deadline
+type ctxLink struct {
+	link contextLink
+	ctx  context.Context
+}
+
+func (l *ctxLink) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return l.link.ExecContext(l.ctx, query, args...)
+}
+
+func (l *ctxLink) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return l.link.QueryContext(l.ctx, query, args...)
+}
+
+func (l *ctxLink) Prepare(query string) (*sql.Stmt, error) {
+	return l.link.PrepareContext(l.ctx, query)
+}
+
+// WithContext return a new DB running its introspection queries and statements with the context,
+// so that canceling it or reaching its deadline interrupts the migration
+func (s *DB) WithContext(ctx context.Context) *DB {
+	return s.withDB(withLink(s.db, ctx, transactionOf(s.db)))
+}
+
+// AutoMigrateContext migrate the models like AutoMigrate, aborting at the statement running when the context is canceled or past its deadline,
+// e.g:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	if err := db.AutoMigrateContext(ctx, &User{}).Error; errors.Is(err, context.DeadlineExceeded) {
+//	    // err tells which statement was interrupted
+//	}
+func (s *DB) AutoMigrateContext(ctx context.Context, values ...interface{}) *DB {
+	return s.WithContext(ctx).AutoMigrate(values...).withDB(s.db)
+}
+
+// contextOf return the context the statements executed through db run with
+func contextOf(db gdb.DB) context.Context {
+	switch db := db.(type) {
+	case *linkDB:
+		return db.ctx
+	case *planDB:
+		return contextOf(db.DB)
+	}
+	return context.Background()
+}
@@ -0,0 +1,67 @@
+package automigrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type ctxUser struct {
+	ID   uint
+	Name string
+}
+
+func TestAutoMigrateContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		mode interface{}
+		want error
+	}{
+		{name: "background", ctx: context.Background()},
+		{name: "canceled", ctx: canceled, want: context.Canceled},
+		{name: "past its deadline", ctx: expired, want: context.DeadlineExceeded},
+		{name: "canceled in batch transaction", ctx: canceled, mode: "batch", want: context.Canceled},
+		{name: "canceled in model transaction", ctx: canceled, mode: "model", want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			if tt.mode != nil {
+				adb = adb.Set("automigrate:transaction", tt.mode)
+			}
+
+			result := adb.AutoMigrateContext(tt.ctx, &ctxUser{})
+			if tt.want == nil {
+				if result.Error != nil {
+					t.Fatalf("AutoMigrateContext error = %v", result.Error)
+				}
+				if got := tables(t, db); len(got) != 1 {
+					t.Errorf("tables = %v, want ctx_user", got)
+				}
+				return
+			}
+			if !errors.Is(result.Error, tt.want) {
+				t.Fatalf("AutoMigrateContext error = %v, want %v", result.Error, tt.want)
+			}
+			if got := tables(t, db); len(got) != 0 {
+				t.Errorf("tables = %v, want none", got)
+			}
+
+			// the returned DB doesn't keep the context
+			result.Error = nil
+			if err := result.AutoMigrate(&ctxUser{}).Error; err != nil {
+				t.Fatalf("AutoMigrate after AutoMigrateContext error = %v", err)
+			}
+			if got := tables(t, db); len(got) != 1 {
+				t.Errorf("tables = %v, want ctx_user", got)
+			}
+		})
+	}
+}
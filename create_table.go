@@ -11,7 +11,8 @@ func (scope *Scope) createJoinTable(field *StructField) {
 	if relationship := field.Relationship; relationship != nil && relationship.JoinTableHandler != nil {
 		joinTableHandler := relationship.JoinTableHandler
 		joinTable := joinTableHandler.Table(scope.db)
-		if !scope.Dialect().HasTable(joinTable) {
+		if !scope.planned(joinTable) && !scope.Dialect().HasTable(joinTable) {
+			scope.createSchema(joinTable)
 			toScope := &Scope{Value: reflect.New(field.Struct.Type).Interface()}
 
 			var sqlTypes, primaryKeys []string
@@ -37,13 +38,34 @@ func (scope *Scope) createJoinTable(field *StructField) {
 				}
 			}
 
-			scope.Err(scope.NewDB().Exec(fmt.Sprintf("CREATE TABLE %v (%v, PRIMARY KEY (%v))%s", scope.Quote(joinTable), strings.Join(sqlTypes, ","), strings.Join(primaryKeys, ","), scope.getTableOptions())).Error)
+			scope.describe(joinTable, "create_table", "", fmt.Sprintf("join table of %v missing", field.Name))
+			scope.Raw(fmt.Sprintf("CREATE TABLE %v (%v, PRIMARY KEY (%v))%s", scope.Quote(joinTable), strings.Join(sqlTypes, ","), strings.Join(primaryKeys, ","), scope.getTableOptions())).Exec()
 		}
-		scope.NewDB().Table(joinTable).AutoMigrate(joinTableHandler)
+		scope.Err(scope.NewDB().Table(joinTable).migrateOwnTable(joinTableHandler))
+	}
+}
+
+// createSchema create the schema of the table if the dialect supports schemas and it doesn't exist yet
+func (scope *Scope) createSchema(tableName string) {
+	dialect, ok := scope.Dialect().(schemaCreator)
+	if !ok || !strings.Contains(tableName, ".") {
+		return
+	}
+
+	schemaName := tableName[:strings.LastIndex(tableName, ".")]
+	if db, ok := scope.db.db.(*planDB); ok && db.dryRun && db.plan.has("create_schema", "", schemaName) {
+		return
+	}
+
+	if !dialect.HasSchema(tableName) {
+		scope.describe("", "create_schema", schemaName, fmt.Sprintf("schema of table %v missing", tableName))
+		scope.Raw(dialect.CreateSchemaSQL(tableName)).Exec()
 	}
 }
 
 func (scope *Scope) createTable() *Scope {
+	scope.createSchema(scope.TableName())
+
 	var tags []string
 	var primaryKeys []string
 	var primaryKeyInColumnType = false
@@ -72,8 +94,15 @@ func (scope *Scope) createTable() *Scope {
 		primaryKeyStr = fmt.Sprintf(", PRIMARY KEY (%v)", strings.Join(primaryKeys, ","))
 	}
 
-	scope.Raw(fmt.Sprintf("CREATE TABLE %v (%v %v)%s", scope.QuotedTableName(), strings.Join(tags, ","), primaryKeyStr, scope.getTableOptions())).Exec()
+	var checkStr string
+	for _, check := range scope.modelChecks() {
+		checkStr += fmt.Sprintf(", CONSTRAINT %v CHECK (%v)", scope.quoteIfPossible(check.name), check.expression)
+	}
+
+	scope.describe(scope.TableName(), "create_table", "", "table missing")
+	scope.Raw(fmt.Sprintf("CREATE TABLE %v (%v %v%v)%s", scope.QuotedTableName(), strings.Join(tags, ","), primaryKeyStr, checkStr, scope.getTableOptions())).Exec()
 
 	scope.autoIndex()
+	scope.autoComment()
 	return scope
 }
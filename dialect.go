@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gogf/gf/database/gdb"
 )
@@ -59,18 +60,185 @@ type Dialect interface {
 	CurrentDatabase() string
 }
 
+// schemaCreator is implemented by dialects whose table names may be qualified with a schema that has to exist before the table is created
+type schemaCreator interface {
+	// HasSchema check has the schema of the table or not
+	HasSchema(tableName string) bool
+	// CreateSchemaSQL return the statement creating the schema of the table
+	CreateSchemaSQL(tableName string) string
+}
+
+// commentDialect is implemented by dialects that store comments apart from the column definitions
+type commentDialect interface {
+	// GetComment return the comment of the table, or of its column if columnName isn't blank, and whether it has one
+	GetComment(tableName, columnName string) (string, bool)
+	// SetCommentSQL return the statement adding the comment, or replacing the existing one if replace is true
+	SetCommentSQL(tableName, columnName, comment string, replace bool) string
+}
+
+// commentStorer is implemented by dialects telling whether the database stores comments, the COMMENT tags are ignored if it doesn't
+type commentStorer interface {
+	// CanStoreComment return false if the database has no comments
+	CanStoreComment() bool
+}
+
+// canStoreComment check the database of the dialect stores comments
+func canStoreComment(dialect Dialect) bool {
+	storer, ok := dialect.(commentStorer)
+	return !ok || storer.CanStoreComment()
+}
+
+// columnDialect is implemented by dialects able to read the definition of existing columns, so that their changes can be migrated
+type columnDialect interface {
+	// Columns return the columns of the table, with their types spelled like DataTypeOf
+	Columns(tableName string) ([]*ColumnInfo, error)
+	// ModifyColumnType return the definition ModifyColumn expects to change the column to the field, typ is the field's type without its constraints
+	ModifyColumnType(field *StructField, column *ColumnInfo, typ string) string
+}
+
+// columnAlterer is implemented by dialects changing the nullability and the default of columns apart from their type,
+// the others get the whole column redefined by ModifyColumn
+type columnAlterer interface {
+	// SetNullSQL return the statements making the column nullable or not, typ is the column's type
+	SetNullSQL(tableName, columnName, typ string, nullable bool) []string
+	// SetDefaultSQL return the statements changing the default of the column, or dropping it if value is blank
+	SetDefaultSQL(tableName, columnName, value string) []string
+}
+
+// catalogDialect is implemented by dialects able to list the tables and indexes of the database, so that the ones missing from the models can be pruned
+type catalogDialect interface {
+	// Tables return the tables of the current database or schema
+	Tables() ([]string, error)
+	// Indexes return the indexes of the table with their columns in order, leaving out the ones backing primary keys and constraints
+	Indexes(tableName string) ([]*IndexInfo, error)
+}
+
+// IndexInfo is the definition of an existing index, read from the database catalog
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Filter  string // condition of a partial index, as the catalog spells it
+}
+
+// checkDialect is implemented by dialects able to read the check constraints of existing tables, so that the ones declared by CHECK tags can be added or replaced
+type checkDialect interface {
+	// Checks return the expressions of the check constraints of the table by constraint name, nil if the database can't read them
+	Checks(tableName string) (map[string]string, error)
+}
+
+// foreignKeyDialect is implemented by dialects able to list the foreign keys of existing tables, so that migrating a table reads them in one query
+type foreignKeyDialect interface {
+	// ForeignKeys return the names of the foreign keys of the table
+	ForeignKeys(tableName string) ([]string, error)
+}
+
+// foreignKeyAdder is implemented by dialects telling whether foreign keys can be added to existing tables
+type foreignKeyAdder interface {
+	// CanAddForeignKey return false if foreign keys can only be declared when creating the table
+	CanAddForeignKey() bool
+}
+
+// foreignKeyDropper is implemented by dialects that don't drop foreign keys with ALTER TABLE ... DROP CONSTRAINT
+type foreignKeyDropper interface {
+	// DropForeignKeySQL return the statement dropping the foreign key of the table
+	DropForeignKeySQL(tableName, foreignKeyName string) string
+}
+
+// checkDropper is implemented by dialects that don't drop check constraints with ALTER TABLE ... DROP CONSTRAINT
+type checkDropper interface {
+	// DropCheckSQL return the statement dropping the check constraint of the table
+	DropCheckSQL(tableName, checkName string) string
+}
+
+// catalogCacher is implemented by dialects reading the names that don't change during the life of a DB, like the current database, through its cache
+type catalogCacher interface {
+	// SetCatalogCache set the cache shared by the DB and its clones
+	SetCatalogCache(cache *CatalogCache)
+}
+
+// CatalogCache keeps the names read from the database catalog that don't change during the life of a DB, like the current database or schema,
+// so that dialects read them once instead of once per table. It's shared by the DB and its clones.
+type CatalogCache struct {
+	l      sync.Mutex
+	values map[string]string
+}
+
+// Load return the cached value of the key, reading it with load if it isn't cached yet.
+// Blank values aren't cached, as reading them may have failed. A nil cache reads the value every time.
+func (c *CatalogCache) Load(key string, load func() string) string {
+	if c == nil {
+		return load()
+	}
+
+	c.l.Lock()
+	value, ok := c.values[key]
+	c.l.Unlock()
+	if ok {
+		return value
+	}
+
+	if value = load(); value != "" {
+		c.l.Lock()
+		if c.values == nil {
+			c.values = map[string]string{}
+		}
+		c.values[key] = value
+		c.l.Unlock()
+	}
+	return value
+}
+
+// columnDropper is implemented by dialects that must remove the objects depending on a column before dropping it
+type columnDropper interface {
+	// DropColumnSQL return the statements dropping the column
+	DropColumnSQL(tableName, columnName string) []string
+}
+
+// columnRenamer is implemented by dialects that don't support ALTER TABLE ... RENAME COLUMN
+type columnRenamer interface {
+	// RenameColumnSQL return the statement renaming the column
+	RenameColumnSQL(tableName, oldName, newName string) string
+}
+
+// tableRenamer is implemented by dialects able to rename tables and their indexes
+type tableRenamer interface {
+	// RenameTableSQL return the statement renaming the table, both names may be qualified with the same schema
+	RenameTableSQL(oldName, newName string) string
+	// RenameIndexSQL return the statements renaming the index of the table
+	RenameIndexSQL(tableName, oldName, newName string) []string
+}
+
+// transactionalDialect is implemented by dialects whose DDL statements can be rolled back, so that migrations may run in transactions
+type transactionalDialect interface {
+	// TransactionalDDL return true if DDL statements run in transactions
+	TransactionalDDL() bool
+}
+
+// lockDialect is implemented by dialects with a lock primitive held by the session taking it, the others lock migrations with a lock table
+type lockDialect interface {
+	// TryLockSQL return the query taking the named lock without waiting, which selects 1 if it was taken and 0 otherwise
+	TryLockSQL(name string) string
+	// UnlockSQL return the statement releasing the named lock
+	UnlockSQL(name string) string
+}
+
 var dialectsMap = map[string]Dialect{}
 
-func newDialect(name string, db gdb.DB) Dialect {
+func newDialect(name string, db gdb.DB, cache *CatalogCache) Dialect {
 	if value, ok := dialectsMap[name]; ok {
 		dialect := reflect.New(reflect.TypeOf(value).Elem()).Interface().(Dialect)
 		dialect.SetDB(db)
+		if cacher, ok := dialect.(catalogCacher); ok {
+			cacher.SetCatalogCache(cache)
+		}
 		return dialect
 	}
 
 	fmt.Printf("`%v` is not officially supported, running under compatibility mode.\n", name)
 	commontDialect := &commonDialect{}
 	commontDialect.SetDB(db)
+	commontDialect.SetCatalogCache(cache)
 	return commontDialect
 }
 
@@ -79,12 +247,77 @@ func RegisterDialect(name string, dialect Dialect) {
 	dialectsMap[name] = dialect
 }
 
+// gdbDialectNames maps the database types of gdb onto the names dialects are registered with
+var gdbDialectNames = map[string]string{
+	"mssql":  "mssql",
+	"mysql":  "mysql",
+	"pgsql":  "postgres",
+	"sqlite": "sqlite3",
+	"oracle": "oracle",
+}
+
+// gdbType returns the database type of db, read from its configuration node or else from its driver
+func gdbType(db gdb.DB) string {
+	for _, node := range gdb.GetConfig(db.GetGroup()) {
+		if node.Type != "" {
+			return node.Type
+		}
+	}
+
+	switch db.(type) {
+	case *gdb.DriverMssql:
+		return "mssql"
+	case *gdb.DriverMysql:
+		return "mysql"
+	case *gdb.DriverPgsql:
+		return "pgsql"
+	case *gdb.DriverSqlite:
+		return "sqlite"
+	case *gdb.DriverOracle:
+		return "oracle"
+	}
+	return ""
+}
+
 // GetDialect gets the dialect for the specified dialect name
 func GetDialect(name string) (dialect Dialect, ok bool) {
 	dialect, ok = dialectsMap[name]
 	return
 }
 
+// DataTypeFunc return the sql type of the field
+type DataTypeFunc func(field *StructField) string
+
+type registeredTypeKey struct {
+	reflectType reflect.Type
+	dialectName string
+}
+
+// global registry of the sql types of go types
+var registeredTypes sync.Map
+
+// RegisterType register the sql type of a go type for the named dialect, or for all dialects if dialectName is blank.
+// The registered type takes precedence over `GormDataType(Dialect)` and the dialect's defaults, but not over the TYPE tag:
+//
+//	automigrate.RegisterType(reflect.TypeOf(decimal.Decimal{}), "", func(*automigrate.StructField) string { return "decimal(20,4)" })
+//	automigrate.RegisterType(reflect.TypeOf(time.Time{}), "mssql", func(*automigrate.StructField) string { return "datetime2" })
+func RegisterType(reflectType reflect.Type, dialectName string, dataType DataTypeFunc) {
+	for reflectType.Kind() == reflect.Ptr {
+		reflectType = reflectType.Elem()
+	}
+	registeredTypes.Store(registeredTypeKey{reflectType, dialectName}, dataType)
+}
+
+// registeredDataType return the sql type registered for the go type in the dialect, or for all dialects
+func registeredDataType(field *StructField, reflectType reflect.Type, dialect Dialect) (string, bool) {
+	for _, dialectName := range []string{dialect.GetName(), ""} {
+		if dataType, ok := registeredTypes.Load(registeredTypeKey{reflectType, dialectName}); ok {
+			return dataType.(DataTypeFunc)(field), true
+		}
+	}
+	return "", false
+}
+
 // ParseFieldStructForDialect get field's sql data type
 var ParseFieldStructForDialect = func(field *StructField, dialect Dialect) (fieldValue reflect.Value, sqlType string, size int, additionalType string) {
 	// Get redirected field type
@@ -100,9 +333,14 @@ var ParseFieldStructForDialect = func(field *StructField, dialect Dialect) (fiel
 	// Get redirected field value
 	fieldValue = reflect.Indirect(reflect.New(reflectType))
 
+	var isRegistered bool
+	if dataType == "" {
+		dataType, isRegistered = registeredDataType(field, reflectType, dialect)
+	}
+
 	if gormDataType, ok := fieldValue.Interface().(interface {
 		GormDataType(Dialect) string
-	}); ok {
+	}); ok && !isRegistered {
 		dataType = gormDataType.GormDataType(dialect)
 	}
 
@@ -133,13 +371,33 @@ var ParseFieldStructForDialect = func(field *StructField, dialect Dialect) (fiel
 		additionalType = additionalType + " DEFAULT " + value
 	}
 
-	if value, ok := field.TagSettingsGet("COMMENT"); ok {
-		additionalType = additionalType + " COMMENT " + value
+	if value, ok := field.TagSettingsGet("COMMENT"); ok && canStoreComment(dialect) {
+		if _, ok := dialect.(commentDialect); !ok {
+			additionalType = additionalType + " COMMENT " + quoteComment(value)
+		}
 	}
 
 	return fieldValue, dataType, size, strings.TrimSpace(additionalType)
 }
 
+// quoteComment wraps the comment from the COMMENT tag in single quotes, leaving already quoted comments untouched
+func quoteComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if len(comment) >= 2 && strings.HasPrefix(comment, "'") && strings.HasSuffix(comment, "'") {
+		return comment
+	}
+	return "'" + strings.Replace(comment, "'", "''", -1) + "'"
+}
+
+// unquoteComment return the text of the comment from the COMMENT tag, removing the quotes it may be wrapped in
+func unquoteComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if len(comment) >= 2 && strings.HasPrefix(comment, "'") && strings.HasSuffix(comment, "'") {
+		return strings.Replace(comment[1:len(comment)-1], "''", "'", -1)
+	}
+	return comment
+}
+
 func currentDatabaseAndTable(dialect Dialect, tableName string) (string, string) {
 	if strings.Contains(tableName, ".") {
 		splitStrings := strings.SplitN(tableName, ".", 2)
@@ -18,7 +18,8 @@ type DefaultForeignKeyNamer struct {
 }
 
 type commonDialect struct {
-	db gdb.DB
+	db      gdb.DB
+	catalog *CatalogCache
 	DefaultForeignKeyNamer
 }
 
@@ -34,6 +35,11 @@ func (s *commonDialect) SetDB(db gdb.DB) {
 	s.db = db
 }
 
+// SetCatalogCache set the cache the current database name is read through
+func (s *commonDialect) SetCatalogCache(cache *CatalogCache) {
+	s.catalog = cache
+}
+
 func (commonDialect) BindVar(i int) string {
 	return "$$$" // ?
 }
@@ -134,9 +140,10 @@ func (s commonDialect) ModifyColumn(tableName string, columnName string, typ str
 }
 
 func (s commonDialect) CurrentDatabase() (name string) {
-	v, _ := s.db.GetValue("SELECT DATABASE() as dbname")
-	name = v.String()
-	return
+	return s.catalog.Load("database", func() string {
+		v, _ := s.db.GetValue("SELECT DATABASE() as dbname")
+		return v.String()
+	})
 }
 
 // LimitAndOffsetSQL return generated SQL with Limit and Offset
@@ -40,7 +40,8 @@ func init() {
 }
 
 type mssql struct {
-	db gdb.DB
+	db      gdb.DB
+	catalog *automigrate.CatalogCache
 	automigrate.DefaultForeignKeyNamer
 }
 
@@ -52,6 +53,11 @@ func (s *mssql) SetDB(db gdb.DB) {
 	s.db = db
 }
 
+// SetCatalogCache set the cache the current database and schema names are read through
+func (s *mssql) SetCatalogCache(cache *automigrate.CatalogCache) {
+	s.catalog = cache
+}
+
 func (mssql) BindVar(i int) string {
 	return "$$$" // ?
 }
@@ -122,46 +128,290 @@ func (s mssql) fieldCanAutoIncrement(field *automigrate.StructField) bool {
 }
 
 func (s mssql) HasIndex(tableName string, indexName string) bool {
-	v, _ := s.db.GetCount("SELECT * FROM sys.indexes WHERE name=? AND object_id=OBJECT_ID(?)", indexName, tableName)
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT * FROM %v.sys.indexes WHERE name = ? AND object_id = OBJECT_ID(?)", s.Quote(currentDatabase)), indexName, s.quoteTable(currentDatabase, currentSchema, tableName))
 	return v > 0
 }
 
 func (s mssql) RemoveIndex(tableName string, indexName string) error {
-	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v ON %v", indexName, s.Quote(tableName)))
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v ON %v", indexName, s.quoteTable(currentDatabase, currentSchema, tableName)))
 	return err
 }
 
 func (s mssql) HasForeignKey(tableName string, foreignKeyName string) bool {
-	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
-	v, _ := s.db.GetCount(`SELECT *
-	FROM sys.foreign_keys as F inner join sys.tables as T on F.parent_object_id=T.object_id 
-		inner join information_schema.tables as I on I.TABLE_NAME = T.name 
-	WHERE F.name = ? 
-		AND T.Name = ? AND I.TABLE_CATALOG = ?;`, foreignKeyName, tableName, currentDatabase)
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	quotedDatabase := s.Quote(currentDatabase)
+	v, _ := s.db.GetCount(fmt.Sprintf(`SELECT *
+	FROM %v.sys.foreign_keys AS F INNER JOIN %v.sys.tables AS T ON F.parent_object_id = T.object_id
+		INNER JOIN %v.sys.schemas AS S ON T.schema_id = S.schema_id
+	WHERE F.name = ?
+		AND T.name = ? AND S.name = ?`, quotedDatabase, quotedDatabase, quotedDatabase), foreignKeyName, tableName, currentSchema)
 	return v > 0
 }
 
 func (s mssql) HasTable(tableName string) bool {
-	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
-	v, _ := s.db.GetCount("SELECT * FROM INFORMATION_SCHEMA.tables WHERE table_name = ? AND table_catalog = ?", tableName, currentDatabase)
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT * FROM %v.INFORMATION_SCHEMA.tables WHERE table_name = ? AND table_schema = ? AND table_catalog = ?", s.Quote(currentDatabase)), tableName, currentSchema, currentDatabase)
 	return v > 0
 }
 
 func (s mssql) HasColumn(tableName string, columnName string) bool {
-	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
-	v, _ := s.db.GetCount("SELECT * FROM information_schema.columns WHERE table_catalog = ? AND table_name = ? AND column_name = ?", currentDatabase, tableName, columnName)
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT * FROM %v.information_schema.columns WHERE table_catalog = ? AND table_schema = ? AND table_name = ? AND column_name = ?", s.Quote(currentDatabase)), currentDatabase, currentSchema, tableName, columnName)
+	return v > 0
+}
+
+// HasSchema check has the schema of the table or not
+func (s mssql) HasSchema(tableName string) bool {
+	currentDatabase, currentSchema, _ := currentDatabaseSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT * FROM %v.sys.schemas WHERE name = ?", s.Quote(currentDatabase)), currentSchema)
 	return v > 0
 }
 
+// CreateSchemaSQL returns the statement creating the schema of the table,
+// it runs through sp_executesql as CREATE SCHEMA must be the only statement in its batch
+func (s mssql) CreateSchemaSQL(tableName string) string {
+	currentDatabase, currentSchema, _ := currentDatabaseSchemaAndTable(&s, tableName)
+	createSchema := strings.Replace(fmt.Sprintf("CREATE SCHEMA %v", s.Quote(currentSchema)), "'", "''", -1)
+	return fmt.Sprintf("EXEC %v.sys.sp_executesql N'%v'", s.Quote(currentDatabase), createSchema)
+}
+
+// GetComment return the MS_Description extended property of the table, or of its column if columnName isn't blank
+func (s mssql) GetComment(tableName, columnName string) (string, bool) {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	quotedTableName := s.quoteTable(currentDatabase, currentSchema, tableName)
+	v, err := s.db.GetAll(fmt.Sprintf(`SELECT CAST(value AS nvarchar(max)) AS comment
+	FROM %v.sys.extended_properties
+	WHERE class = 1 AND name = 'MS_Description' AND major_id = OBJECT_ID(?)
+		AND minor_id = COALESCE(COLUMNPROPERTY(OBJECT_ID(?), ?, 'ColumnId'), 0)`, s.Quote(currentDatabase)), quotedTableName, quotedTableName, columnName)
+	if err != nil || len(v) == 0 {
+		return "", false
+	}
+	return v[0]["comment"].String(), true
+}
+
+// SetCommentSQL return the statement adding or updating the MS_Description extended property of the table, or of its column if columnName isn't blank
+func (s mssql) SetCommentSQL(tableName, columnName, comment string, replace bool) string {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	procedure := "sp_addextendedproperty"
+	if replace {
+		procedure = "sp_updateextendedproperty"
+	}
+
+	sql := fmt.Sprintf("EXEC %v.sys.%v @name = N'MS_Description', @value = %v, @level0type = N'SCHEMA', @level0name = %v, @level1type = N'TABLE', @level1name = %v",
+		s.Quote(currentDatabase), procedure, quoteString(comment), quoteString(currentSchema), quoteString(tableName))
+	if columnName != "" {
+		sql += fmt.Sprintf(", @level2type = N'COLUMN', @level2name = %v", quoteString(columnName))
+	}
+	return sql
+}
+
+// Columns return the columns of the table read from INFORMATION_SCHEMA
+func (s mssql) Columns(tableName string) ([]*automigrate.ColumnInfo, error) {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	result, err := s.db.GetAll(fmt.Sprintf(`SELECT column_name AS name, data_type AS type, character_maximum_length AS size, numeric_precision AS num_precision, numeric_scale AS num_scale, is_nullable AS nullable, column_default AS column_default
+	FROM %v.information_schema.columns
+	WHERE table_catalog = ? AND table_schema = ? AND table_name = ?
+	ORDER BY ordinal_position`, s.Quote(currentDatabase)), currentDatabase, currentSchema, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []*automigrate.ColumnInfo
+	for _, record := range result {
+		column := &automigrate.ColumnInfo{
+			Name:     record["name"].String(),
+			Type:     record["type"].String(),
+			Nullable: record["nullable"].String() == "YES",
+		}
+		switch column.Type {
+		case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+			if size := record["size"].Int(); size == -1 {
+				column.Type += "(max)"
+			} else {
+				column.Type += fmt.Sprintf("(%d)", size)
+			}
+		case "decimal", "numeric":
+			column.Type += fmt.Sprintf("(%d,%d)", record["num_precision"].Int(), record["num_scale"].Int())
+		}
+		if value := record["column_default"]; !value.IsNil() {
+			column.Default.String, column.Default.Valid = value.String(), true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ModifyColumnType return the type of the field with the column's nullability, as ALTER COLUMN makes columns nullable otherwise
+func (mssql) ModifyColumnType(field *automigrate.StructField, column *automigrate.ColumnInfo, typ string) string {
+	if column.Nullable {
+		return typ + " NULL"
+	}
+	return typ + " NOT NULL"
+}
+
+// SetNullSQL return the statement making the column nullable or not, ALTER COLUMN requiring the column's type
+func (s mssql) SetNullSQL(tableName, columnName, typ string, nullable bool) []string {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	constraint := "NOT NULL"
+	if nullable {
+		constraint = "NULL"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v %v %v", s.quoteTable(currentDatabase, currentSchema, tableName), s.Quote(columnName), typ, constraint)}
+}
+
+// SetDefaultSQL return the statements dropping the default constraint of the column,
+// usually named by the system like DF__users__name__1A2B3C4D, then adding a named one with the new value
+func (s mssql) SetDefaultSQL(tableName, columnName, value string) []string {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	quotedDatabase := s.Quote(currentDatabase)
+	quotedTableName := s.quoteTable(currentDatabase, currentSchema, tableName)
+
+	var sqls []string
+	constraint, err := s.db.GetValue(fmt.Sprintf(`SELECT D.name
+	FROM %v.sys.default_constraints AS D INNER JOIN %v.sys.columns AS C ON C.object_id = D.parent_object_id AND C.column_id = D.parent_column_id
+	WHERE D.parent_object_id = OBJECT_ID(?) AND C.name = ?`, quotedDatabase, quotedDatabase), quotedTableName, columnName)
+	if err == nil && constraint != nil && !constraint.IsNil() {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v", quotedTableName, s.Quote(constraint.String())))
+	}
+	if value != "" {
+		sqls = append(sqls, fmt.Sprintf("ALTER TABLE %v ADD CONSTRAINT %v DEFAULT %v FOR %v", quotedTableName, s.Quote(s.BuildKeyName("df", tableName, columnName)), value, s.Quote(columnName)))
+	}
+	return sqls
+}
+
+// Tables return the tables of the user's default schema
+func (s mssql) Tables() ([]string, error) {
+	v, err := s.db.GetArray("SELECT table_name FROM INFORMATION_SCHEMA.tables WHERE table_schema = SCHEMA_NAME() AND table_type = 'BASE TABLE'")
+	var tables []string
+	for _, tableName := range v {
+		tables = append(tables, tableName.String())
+	}
+	return tables, err
+}
+
+// Indexes return the indexes of the table and the condition of the filtered ones, except the ones backing the primary key and unique constraints
+func (s mssql) Indexes(tableName string) ([]*automigrate.IndexInfo, error) {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	quotedDatabase := s.Quote(currentDatabase)
+	result, err := s.db.GetAll(fmt.Sprintf(`SELECT I.name AS name, C.name AS column_name, I.is_unique AS is_unique, I.filter_definition AS filter
+	FROM %v.sys.indexes AS I
+		INNER JOIN %v.sys.index_columns AS IC ON IC.object_id = I.object_id AND IC.index_id = I.index_id
+		INNER JOIN %v.sys.columns AS C ON C.object_id = IC.object_id AND C.column_id = IC.column_id
+	WHERE I.object_id = OBJECT_ID(?) AND I.is_primary_key = 0 AND I.is_unique_constraint = 0 AND IC.is_included_column = 0
+	ORDER BY I.name, IC.key_ordinal`, quotedDatabase, quotedDatabase, quotedDatabase), s.quoteTable(currentDatabase, currentSchema, tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*automigrate.IndexInfo
+	for _, record := range result {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != record["name"].String() {
+			indexes = append(indexes, &automigrate.IndexInfo{Name: record["name"].String(), Unique: record["is_unique"].Bool(), Filter: record["filter"].String()})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, record["column_name"].String())
+	}
+	return indexes, nil
+}
+
+// Checks return the check constraints of the table from sys.check_constraints
+func (s mssql) Checks(tableName string) (map[string]string, error) {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	result, err := s.db.GetAll(fmt.Sprintf("SELECT name, definition FROM %v.sys.check_constraints WHERE parent_object_id = OBJECT_ID(?)", s.Quote(currentDatabase)), s.quoteTable(currentDatabase, currentSchema, tableName))
+	checks := map[string]string{}
+	for _, record := range result {
+		checks[record["name"].String()] = record["definition"].String()
+	}
+	return checks, err
+}
+
+// ForeignKeys return the names of the foreign keys of the table from sys.foreign_keys
+func (s mssql) ForeignKeys(tableName string) ([]string, error) {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	v, err := s.db.GetArray(fmt.Sprintf("SELECT name FROM %v.sys.foreign_keys WHERE parent_object_id = OBJECT_ID(?)", s.Quote(currentDatabase)), s.quoteTable(currentDatabase, currentSchema, tableName))
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names, err
+}
+
+// DropColumnSQL return the statements dropping the default constraint of the column, that would prevent it from being dropped, then the column
+func (s mssql) DropColumnSQL(tableName, columnName string) []string {
+	currentDatabase, currentSchema, table := currentDatabaseSchemaAndTable(&s, tableName)
+	sqls := s.SetDefaultSQL(tableName, columnName, "")
+	return append(sqls, fmt.Sprintf("ALTER TABLE %v DROP COLUMN %v", s.quoteTable(currentDatabase, currentSchema, table), s.Quote(columnName)))
+}
+
+// RenameColumnSQL return the statement renaming the column with sp_rename
+func (s mssql) RenameColumnSQL(tableName, oldName, newName string) string {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	return fmt.Sprintf("EXEC %v.sys.sp_rename %v, %v, N'COLUMN'", s.Quote(currentDatabase), quoteString(s.quoteTable(currentSchema, tableName, oldName)), quoteString(newName))
+}
+
+// RenameTableSQL return the statement renaming the table with sp_rename, keeping it in its schema
+func (s mssql) RenameTableSQL(oldName, newName string) string {
+	currentDatabase, currentSchema, oldName := currentDatabaseSchemaAndTable(&s, oldName)
+	_, _, newName = currentDatabaseSchemaAndTable(&s, newName)
+	return fmt.Sprintf("EXEC %v.sys.sp_rename %v, %v", s.Quote(currentDatabase), quoteString(s.quoteTable(currentSchema, oldName)), quoteString(newName))
+}
+
+// RenameIndexSQL return the statement renaming the index with sp_rename
+func (s mssql) RenameIndexSQL(tableName, oldName, newName string) []string {
+	currentDatabase, currentSchema, tableName := currentDatabaseSchemaAndTable(&s, tableName)
+	return []string{fmt.Sprintf("EXEC %v.sys.sp_rename %v, %v, N'INDEX'", s.Quote(currentDatabase), quoteString(s.quoteTable(currentSchema, tableName, oldName)), quoteString(newName))}
+}
+
+// TryLockSQL return the batch taking the named application lock of the session with sp_getapplock, without waiting
+func (mssql) TryLockSQL(name string) string {
+	return fmt.Sprintf(`DECLARE @result int;
+EXEC @result = sp_getapplock @Resource = %v, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+SELECT CASE WHEN @result >= 0 THEN 1 ELSE 0 END`, quoteString(name))
+}
+
+// UnlockSQL return the statement releasing the named application lock of the session
+func (mssql) UnlockSQL(name string) string {
+	return fmt.Sprintf("EXEC sp_releaseapplock @Resource = %v, @LockOwner = 'Session'", quoteString(name))
+}
+
+// TransactionalDDL return true, mssql rolls back DDL statements with the transaction
+func (mssql) TransactionalDDL() bool {
+	return true
+}
+
 func (s mssql) ModifyColumn(tableName string, columnName string, typ string) error {
 	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v %v", tableName, columnName, typ))
 	return err
 }
 
 func (s mssql) CurrentDatabase() (name string) {
-	v, _ := s.db.GetValue("SELECT DB_NAME() AS [Current Database]")
-	name = v.String()
-	return
+	return s.catalog.Load("database", func() string {
+		v, _ := s.db.GetValue("SELECT DB_NAME() AS [Current Database]")
+		return v.String()
+	})
+}
+
+// CurrentSchema return the default schema of the current user
+func (s mssql) CurrentSchema() (name string) {
+	return s.catalog.Load("schema", func() string {
+		v, _ := s.db.GetValue("SELECT SCHEMA_NAME() AS [Current Schema]")
+		return v.String()
+	})
+}
+
+// quoteString returns str as an unicode string literal
+func quoteString(str string) string {
+	return "N'" + strings.Replace(str, "'", "''", -1) + "'"
+}
+
+func (s mssql) quoteTable(names ...string) string {
+	var quoted []string
+	for _, name := range names {
+		quoted = append(quoted, s.Quote(name))
+	}
+	return strings.Join(quoted, ".")
 }
 
 func parseInt(value interface{}) (int64, error) {
@@ -216,12 +466,17 @@ func (mssql) NormalizeIndexAndColumn(indexName, columnName string) (string, stri
 	return indexName, columnName
 }
 
-func currentDatabaseAndTable(dialect automigrate.Dialect, tableName string) (string, string) {
-	if strings.Contains(tableName, ".") {
-		splitStrings := strings.SplitN(tableName, ".", 2)
-		return splitStrings[0], splitStrings[1]
+// currentDatabaseSchemaAndTable splits `table`, `schema.table` and `database.schema.table`,
+// the parts left out are the current database and the user's default schema
+func currentDatabaseSchemaAndTable(dialect *mssql, tableName string) (string, string, string) {
+	splitStrings := strings.SplitN(tableName, ".", 3)
+	switch len(splitStrings) {
+	case 3:
+		return splitStrings[0], splitStrings[1], splitStrings[2]
+	case 2:
+		return dialect.CurrentDatabase(), splitStrings[0], splitStrings[1]
 	}
-	return dialect.CurrentDatabase(), tableName
+	return dialect.CurrentDatabase(), dialect.CurrentSchema(), tableName
 }
 
 // JSON type to support easy handling of JSON data in character table fields
@@ -0,0 +1,492 @@
+package mysql
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/database/gdb"
+	"github.com/sanrentai/automigrate"
+)
+
+var (
+	keyNameRegex    = regexp.MustCompile("[^a-zA-Z0-9]+")
+	mysqlIndexRegex = regexp.MustCompile(`^(.+)\((\d+)\)$`)
+)
+
+func init() {
+	automigrate.RegisterDialect("mysql", &mysql{})
+}
+
+type mysql struct {
+	db      gdb.DB
+	catalog *automigrate.CatalogCache
+	automigrate.DefaultForeignKeyNamer
+}
+
+func (mysql) GetName() string {
+	return "mysql"
+}
+
+func (s *mysql) SetDB(db gdb.DB) {
+	s.db = db
+}
+
+// SetCatalogCache set the cache the current database name is read through
+func (s *mysql) SetCatalogCache(cache *automigrate.CatalogCache) {
+	s.catalog = cache
+}
+
+func (mysql) BindVar(i int) string {
+	return "$$$" // ?
+}
+
+func (mysql) Quote(key string) string {
+	return fmt.Sprintf("`%s`", key)
+}
+
+// DataTypeOf get data type from dialector
+func (s *mysql) DataTypeOf(field *automigrate.StructField) string {
+	var dataValue, sqlType, size, additionalType = automigrate.ParseFieldStructForDialect(field, s)
+
+	// MySQL allows only one auto increment column per table, and it must
+	// be a KEY column.
+	if _, ok := field.TagSettingsGet("AUTO_INCREMENT"); ok {
+		if _, ok = field.TagSettingsGet("INDEX"); !ok && !field.IsPrimaryKey {
+			field.TagSettingsDelete("AUTO_INCREMENT")
+		}
+	}
+
+	if sqlType == "" {
+		switch dataValue.Kind() {
+		case reflect.Bool:
+			sqlType = "tinyint(1)"
+		case reflect.Int8:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "tinyint AUTO_INCREMENT"
+			} else {
+				sqlType = "tinyint"
+			}
+		case reflect.Int, reflect.Int16, reflect.Int32:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "int AUTO_INCREMENT"
+			} else {
+				sqlType = "int"
+			}
+		case reflect.Uint8:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "tinyint unsigned AUTO_INCREMENT"
+			} else {
+				sqlType = "tinyint unsigned"
+			}
+		case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "int unsigned AUTO_INCREMENT"
+			} else {
+				sqlType = "int unsigned"
+			}
+		case reflect.Int64:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "bigint AUTO_INCREMENT"
+			} else {
+				sqlType = "bigint"
+			}
+		case reflect.Uint64:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "bigint unsigned AUTO_INCREMENT"
+			} else {
+				sqlType = "bigint unsigned"
+			}
+		case reflect.Float32, reflect.Float64:
+			sqlType = "double"
+		case reflect.String:
+			if size > 0 && size < 65532 {
+				sqlType = fmt.Sprintf("varchar(%d)", size)
+			} else {
+				sqlType = "longtext"
+			}
+		case reflect.Struct:
+			if _, ok := dataValue.Interface().(time.Time); ok {
+				precision := "3"
+				if p, ok := field.TagSettingsGet("PRECISION"); ok {
+					precision = p
+				}
+
+				if _, ok := field.TagSettingsGet("NOT NULL"); ok || field.IsPrimaryKey {
+					sqlType = fmt.Sprintf("datetime(%v)", precision)
+				} else {
+					sqlType = fmt.Sprintf("datetime(%v) NULL", precision)
+				}
+			}
+		default:
+			if automigrate.IsByteArrayOrSlice(dataValue) {
+				if size > 0 && size < 65532 {
+					sqlType = fmt.Sprintf("varbinary(%d)", size)
+				} else {
+					sqlType = "longblob"
+				}
+			}
+		}
+	}
+
+	if sqlType == "" {
+		panic(fmt.Sprintf("invalid sql type %s (%s) in field %s for mysql", dataValue.Type().Name(), dataValue.Kind().String(), field.Name))
+	}
+
+	if strings.TrimSpace(additionalType) == "" {
+		return sqlType
+	}
+	return fmt.Sprintf("%v %v", sqlType, additionalType)
+}
+
+func (s mysql) fieldCanAutoIncrement(field *automigrate.StructField) bool {
+	if value, ok := field.TagSettingsGet("AUTO_INCREMENT"); ok {
+		return strings.ToLower(value) != "false"
+	}
+	return field.IsPrimaryKey
+}
+
+func (s mysql) HasIndex(tableName string, indexName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT * FROM INFORMATION_SCHEMA.STATISTICS WHERE table_schema = ? AND table_name = ? AND index_name = ?", currentDatabase, tableName, indexName)
+	return v > 0
+}
+
+func (s mysql) RemoveIndex(tableName string, indexName string) error {
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v ON %v", indexName, s.Quote(tableName)))
+	return err
+}
+
+func (s mysql) HasForeignKey(tableName string, foreignKeyName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = ? AND CONSTRAINT_TYPE = 'FOREIGN KEY'", currentDatabase, tableName, foreignKeyName)
+	return v > 0
+}
+
+func (s mysql) HasTable(tableName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT * FROM INFORMATION_SCHEMA.TABLES WHERE table_schema = ? AND table_name = ?", currentDatabase, tableName)
+	return v > 0
+}
+
+func (s mysql) HasColumn(tableName string, columnName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT * FROM INFORMATION_SCHEMA.COLUMNS WHERE table_schema = ? AND table_name = ? AND column_name = ?", currentDatabase, tableName, columnName)
+	return v > 0
+}
+
+// Columns return the columns of the table read from INFORMATION_SCHEMA
+func (s mysql) Columns(tableName string) ([]*automigrate.ColumnInfo, error) {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	result, err := s.db.GetAll("SELECT column_name AS name, column_type AS type, is_nullable AS nullable, column_default AS column_default FROM INFORMATION_SCHEMA.COLUMNS WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position", currentDatabase, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []*automigrate.ColumnInfo
+	for _, record := range result {
+		column := &automigrate.ColumnInfo{
+			Name:     record["name"].String(),
+			Type:     record["type"].String(),
+			Nullable: record["nullable"].String() == "YES",
+		}
+		if value := record["column_default"]; !value.IsNil() {
+			column.Default.String, column.Default.Valid = value.String(), true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ModifyColumnType return the full definition of the field, as MODIFY COLUMN resets everything left out,
+// except UNIQUE that would add another index
+func (s *mysql) ModifyColumnType(field *automigrate.StructField, column *automigrate.ColumnInfo, typ string) string {
+	if unique, ok := field.TagSettingsGet("UNIQUE"); ok {
+		field.TagSettingsDelete("UNIQUE")
+		defer field.TagSettingsSet("UNIQUE", unique)
+	}
+	return s.DataTypeOf(field)
+}
+
+// Tables return the tables of the current database
+func (s mysql) Tables() ([]string, error) {
+	v, err := s.db.GetArray("SELECT table_name FROM INFORMATION_SCHEMA.TABLES WHERE table_schema = ? AND table_type = 'BASE TABLE'", s.CurrentDatabase())
+	var tables []string
+	for _, tableName := range v {
+		tables = append(tables, tableName.String())
+	}
+	return tables, err
+}
+
+// Indexes return the indexes of the table, except the primary key
+func (s mysql) Indexes(tableName string) ([]*automigrate.IndexInfo, error) {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	result, err := s.db.GetAll("SELECT index_name AS name, column_name AS column_name, non_unique AS non_unique FROM INFORMATION_SCHEMA.STATISTICS WHERE table_schema = ? AND table_name = ? AND index_name <> 'PRIMARY' ORDER BY index_name, seq_in_index", currentDatabase, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*automigrate.IndexInfo
+	for _, record := range result {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != record["name"].String() {
+			indexes = append(indexes, &automigrate.IndexInfo{Name: record["name"].String(), Unique: record["non_unique"].Int() == 0})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, record["column_name"].String())
+	}
+	return indexes, nil
+}
+
+// Checks return the check constraints of the table, enforced since mysql 8.0.16, nil for older versions lacking CHECK_CONSTRAINTS
+func (s mysql) Checks(tableName string) (map[string]string, error) {
+	if !s.hasCheckConstraints() {
+		return nil, nil
+	}
+
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	result, err := s.db.GetAll(`SELECT cc.CONSTRAINT_NAME AS name, cc.CHECK_CLAUSE AS expression
+	FROM INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc
+		INNER JOIN INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc ON tc.CONSTRAINT_SCHEMA = cc.CONSTRAINT_SCHEMA AND tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
+	WHERE tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ? AND tc.CONSTRAINT_TYPE = 'CHECK'`, currentDatabase, tableName)
+	checks := map[string]string{}
+	for _, record := range result {
+		checks[record["name"].String()] = record["expression"].String()
+	}
+	return checks, err
+}
+
+// hasCheckConstraints check INFORMATION_SCHEMA has the CHECK_CONSTRAINTS view, added by mysql 8.0.16 and mariadb 10.2
+func (s mysql) hasCheckConstraints() bool {
+	return s.catalog.Load("check_constraints", func() string {
+		v, err := s.db.GetCount("SELECT count(*) FROM INFORMATION_SCHEMA.TABLES WHERE table_schema = 'information_schema' AND table_name = 'CHECK_CONSTRAINTS'")
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatBool(v > 0)
+	}) == "true"
+}
+
+// serverVersion return the version of the server, like 8.0.16-log or 10.5.8-MariaDB
+func (s mysql) serverVersion() string {
+	return s.catalog.Load("version", func() string {
+		v, _ := s.db.GetValue("SELECT VERSION()")
+		return v.String()
+	})
+}
+
+// isMariaDB check the server is mariadb, whose versions don't compare with mysql ones
+func (s mysql) isMariaDB() bool {
+	return strings.Contains(strings.ToLower(s.serverVersion()), "mariadb")
+}
+
+// mysqlAtLeast check the server is mysql of the version or a later one
+func (s mysql) mysqlAtLeast(version ...int) bool {
+	if s.isMariaDB() {
+		return false
+	}
+	numbers := strings.Split(strings.SplitN(s.serverVersion(), "-", 2)[0], ".")
+	for i, want := range version {
+		var number int
+		if i < len(numbers) {
+			number, _ = strconv.Atoi(numbers[i])
+		}
+		if number != want {
+			return number > want
+		}
+	}
+	return true
+}
+
+// ForeignKeys return the names of the foreign keys of the table
+func (s mysql) ForeignKeys(tableName string) ([]string, error) {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, err := s.db.GetArray("SELECT CONSTRAINT_NAME FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_TYPE = 'FOREIGN KEY'", currentDatabase, tableName)
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names, err
+}
+
+// DropForeignKeySQL return the statement dropping the foreign key with DROP FOREIGN KEY, as DROP CONSTRAINT needs mysql 8.0.19
+func (s mysql) DropForeignKeySQL(tableName, foreignKeyName string) string {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	return fmt.Sprintf("ALTER TABLE %v.%v DROP FOREIGN KEY %v", s.Quote(currentDatabase), s.Quote(tableName), s.Quote(foreignKeyName))
+}
+
+// RenameColumnSQL return the statement renaming the column, with CHANGE COLUMN and the column's definition
+// read from SHOW CREATE TABLE before mysql 8.0 and on mariadb, which lack RENAME COLUMN or got it later
+func (s mysql) RenameColumnSQL(tableName, oldName, newName string) string {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	quotedTableName := s.Quote(currentDatabase) + "." + s.Quote(tableName)
+	if !s.mysqlAtLeast(8) {
+		if definition := s.columnDefinition(quotedTableName, oldName); definition != "" {
+			return fmt.Sprintf("ALTER TABLE %v CHANGE COLUMN %v %v %v", quotedTableName, s.Quote(oldName), s.Quote(newName), definition)
+		}
+	}
+	return fmt.Sprintf("ALTER TABLE %v RENAME COLUMN %v TO %v", quotedTableName, s.Quote(oldName), s.Quote(newName))
+}
+
+// columnDefinition return the definition of the column following its name in SHOW CREATE TABLE, blank if it isn't found
+func (s mysql) columnDefinition(quotedTableName, columnName string) string {
+	record, err := s.db.GetOne("SHOW CREATE TABLE " + quotedTableName)
+	if err != nil {
+		return ""
+	}
+
+	prefix := s.Quote(strings.Replace(columnName, "`", "``", -1)) + " "
+	for _, line := range strings.Split(record["Create Table"].String(), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, prefix) {
+			return strings.TrimSuffix(line[len(prefix):], ",")
+		}
+	}
+	return ""
+}
+
+// DropCheckSQL return the statement dropping the check constraint with DROP CHECK on mysql, as DROP CONSTRAINT needs mysql 8.0.19,
+// and with DROP CONSTRAINT on mariadb, which has no DROP CHECK
+func (s mysql) DropCheckSQL(tableName, checkName string) string {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	clause := "CHECK"
+	if s.isMariaDB() {
+		clause = "CONSTRAINT"
+	}
+	return fmt.Sprintf("ALTER TABLE %v.%v DROP %v %v", s.Quote(currentDatabase), s.Quote(tableName), clause, s.Quote(checkName))
+}
+
+// RenameTableSQL return the statement renaming the table, keeping it in its database
+func (s mysql) RenameTableSQL(oldName, newName string) string {
+	currentDatabase, oldName := currentDatabaseAndTable(&s, oldName)
+	_, newName = currentDatabaseAndTable(&s, newName)
+	return fmt.Sprintf("ALTER TABLE %v.%v RENAME TO %v.%v", s.Quote(currentDatabase), s.Quote(oldName), s.Quote(currentDatabase), s.Quote(newName))
+}
+
+// RenameIndexSQL return the statement renaming the index, supported since mysql 5.7
+func (s mysql) RenameIndexSQL(tableName, oldName, newName string) []string {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	return []string{fmt.Sprintf("ALTER TABLE %v.%v RENAME INDEX %v TO %v", s.Quote(currentDatabase), s.Quote(tableName), oldName, newName)}
+}
+
+// TryLockSQL return the query taking the named lock with GET_LOCK, without waiting
+func (mysql) TryLockSQL(name string) string {
+	return fmt.Sprintf("SELECT COALESCE(GET_LOCK(%v, 0), 0)", quoteString(name))
+}
+
+// UnlockSQL return the statement releasing the named lock
+func (mysql) UnlockSQL(name string) string {
+	return fmt.Sprintf("SELECT RELEASE_LOCK(%v)", quoteString(name))
+}
+
+// TransactionalDDL return false, as DDL statements commit the transaction implicitly
+func (mysql) TransactionalDDL() bool {
+	return false
+}
+
+func (s mysql) ModifyColumn(tableName string, columnName string, typ string) error {
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %v MODIFY COLUMN %v %v", tableName, columnName, typ))
+	return err
+}
+
+func (s mysql) CurrentDatabase() (name string) {
+	return s.catalog.Load("database", func() string {
+		v, _ := s.db.GetValue("SELECT DATABASE()")
+		return v.String()
+	})
+}
+
+func parseInt(value interface{}) (int64, error) {
+	return strconv.ParseInt(fmt.Sprint(value), 0, 0)
+}
+
+func (mysql) LimitAndOffsetSQL(limit, offset interface{}) (sql string, err error) {
+	if limit != nil {
+		parsedLimit, err := parseInt(limit)
+		if err != nil {
+			return "", err
+		}
+		if parsedLimit >= 0 {
+			sql += fmt.Sprintf(" LIMIT %d", parsedLimit)
+
+			if offset != nil {
+				parsedOffset, err := parseInt(offset)
+				if err != nil {
+					return "", err
+				}
+				if parsedOffset >= 0 {
+					sql += fmt.Sprintf(" OFFSET %d", parsedOffset)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (mysql) SelectFromDummyTable() string {
+	return "FROM DUAL"
+}
+
+func (mysql) LastInsertIDOutputInterstitial(tableName, columnName string, columns []string) string {
+	return ""
+}
+
+func (mysql) LastInsertIDReturningSuffix(tableName, columnName string) string {
+	return ""
+}
+
+func (mysql) DefaultValueStr() string {
+	return "VALUES()"
+}
+
+// BuildKeyName returns a valid key name (foreign key, index key) for the given table, field and reference,
+// hashing names that exceed mysql's 64 character limit
+func (s mysql) BuildKeyName(kind, tableName string, fields ...string) string {
+	keyName := s.DefaultForeignKeyNamer.BuildKeyName(kind, tableName, fields...)
+	if utf8.RuneCountInString(keyName) <= 64 {
+		return keyName
+	}
+	h := sha1.New()
+	h.Write([]byte(keyName))
+	bs := h.Sum(nil)
+
+	// sha1 is 40 characters, keep first 24 characters of destination
+	destRunes := []rune(keyNameRegex.ReplaceAllString(fields[0], "_"))
+	if len(destRunes) > 24 {
+		destRunes = destRunes[:24]
+	}
+
+	return fmt.Sprintf("%s%x", string(destRunes), bs)
+}
+
+// NormalizeIndexAndColumn returns index name and column name for specify an index prefix length if needed
+func (mysql) NormalizeIndexAndColumn(indexName, columnName string) (string, string) {
+	submatch := mysqlIndexRegex.FindStringSubmatch(indexName)
+	if len(submatch) != 3 {
+		return indexName, columnName
+	}
+	indexName = submatch[1]
+	columnName = fmt.Sprintf("%s(%s)", columnName, submatch[2])
+	return indexName, columnName
+}
+
+func quoteString(str string) string {
+	return "'" + strings.Replace(str, "'", "''", -1) + "'"
+}
+
+func currentDatabaseAndTable(dialect automigrate.Dialect, tableName string) (string, string) {
+	if strings.Contains(tableName, ".") {
+		splitStrings := strings.SplitN(tableName, ".", 2)
+		return splitStrings[0], splitStrings[1]
+	}
+	return dialect.CurrentDatabase(), tableName
+}
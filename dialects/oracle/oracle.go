@@ -0,0 +1,421 @@
+package oracle
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/database/gdb"
+	"github.com/sanrentai/automigrate"
+)
+
+// MaxIdentifierLength is the longest identifier oracle accepts, 30 before 12.2, set it to 128 for newer servers
+var MaxIdentifierLength = 30
+
+func init() {
+	automigrate.RegisterDialect("oracle", &oracle{})
+}
+
+type oracle struct {
+	db      gdb.DB
+	catalog *automigrate.CatalogCache
+	automigrate.DefaultForeignKeyNamer
+}
+
+func (oracle) GetName() string {
+	return "oracle"
+}
+
+func (s *oracle) SetDB(db gdb.DB) {
+	s.db = db
+}
+
+// SetCatalogCache set the cache the current schema name is read through
+func (s *oracle) SetCatalogCache(cache *automigrate.CatalogCache) {
+	s.catalog = cache
+}
+
+func (oracle) BindVar(i int) string {
+	return "$$$" // ?
+}
+
+// Quote quotes the name upper cased, as quoted names are case sensitive and the data dictionary is queried with the upper cased names
+func (oracle) Quote(key string) string {
+	return fmt.Sprintf(`"%s"`, strings.ToUpper(key))
+}
+
+func (s *oracle) DataTypeOf(field *automigrate.StructField) string {
+	var dataValue, sqlType, size, _ = automigrate.ParseFieldStructForDialect(field, s)
+
+	if sqlType == "" {
+		switch dataValue.Kind() {
+		case reflect.Bool:
+			sqlType = "NUMBER(1)"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "NUMBER(10) GENERATED BY DEFAULT ON NULL AS IDENTITY"
+			} else {
+				sqlType = "NUMBER(10)"
+			}
+		case reflect.Int64, reflect.Uint64:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "NUMBER(19) GENERATED BY DEFAULT ON NULL AS IDENTITY"
+			} else {
+				sqlType = "NUMBER(19)"
+			}
+		case reflect.Float32, reflect.Float64:
+			sqlType = "NUMBER"
+		case reflect.String:
+			if size > 0 && size <= 4000 {
+				sqlType = fmt.Sprintf("VARCHAR2(%d)", size)
+			} else {
+				sqlType = "CLOB"
+			}
+		case reflect.Struct:
+			if _, ok := dataValue.Interface().(time.Time); ok {
+				sqlType = "TIMESTAMP WITH TIME ZONE"
+			}
+		default:
+			if automigrate.IsByteArrayOrSlice(dataValue) {
+				if size > 0 && size <= 2000 {
+					sqlType = fmt.Sprintf("RAW(%d)", size)
+				} else {
+					sqlType = "BLOB"
+				}
+			}
+		}
+	}
+
+	if sqlType == "" {
+		panic(fmt.Sprintf("invalid sql type %s (%s) for oracle", dataValue.Type().Name(), dataValue.Kind().String()))
+	}
+
+	// oracle requires DEFAULT before the constraints and has no inline COMMENT,
+	// so the additional type is built from the tag settings here
+	var additionalType string
+	if value, ok := field.TagSettingsGet("DEFAULT"); ok {
+		additionalType = additionalType + " DEFAULT " + value
+	}
+	if value, ok := field.TagSettingsGet("NOT NULL"); ok {
+		additionalType = additionalType + " " + value
+	}
+	if value, ok := field.TagSettingsGet("UNIQUE"); ok {
+		additionalType = additionalType + " " + value
+	}
+
+	if strings.TrimSpace(additionalType) == "" {
+		return sqlType
+	}
+	return fmt.Sprintf("%v %v", sqlType, strings.TrimSpace(additionalType))
+}
+
+func (s oracle) fieldCanAutoIncrement(field *automigrate.StructField) bool {
+	if value, ok := field.TagSettingsGet("AUTO_INCREMENT"); ok {
+		return strings.ToLower(value) != "false"
+	}
+	return field.IsPrimaryKey
+}
+
+func (s oracle) HasIndex(tableName string, indexName string) bool {
+	from, args := dictionary("INDEXES", tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM "+from+" AND INDEX_NAME = UPPER(?)", append(args, indexName)...)
+	return v > 0
+}
+
+func (s oracle) RemoveIndex(tableName string, indexName string) error {
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v", indexName))
+	return err
+}
+
+func (s oracle) HasForeignKey(tableName string, foreignKeyName string) bool {
+	from, args := dictionary("CONSTRAINTS", tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM "+from+" AND CONSTRAINT_NAME = UPPER(?) AND CONSTRAINT_TYPE = 'R'", append(args, foreignKeyName)...)
+	return v > 0
+}
+
+func (s oracle) HasTable(tableName string) bool {
+	from, args := dictionary("TABLES", tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM "+from, args...)
+	return v > 0
+}
+
+func (s oracle) HasColumn(tableName string, columnName string) bool {
+	from, args := dictionary("TAB_COLUMNS", tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM "+from+" AND COLUMN_NAME = UPPER(?)", append(args, columnName)...)
+	return v > 0
+}
+
+// GetComment return the comment of the table, or of its column if columnName isn't blank
+func (s oracle) GetComment(tableName, columnName string) (string, bool) {
+	var (
+		v   gdb.Value
+		err error
+	)
+	if columnName == "" {
+		from, args := dictionary("TAB_COMMENTS", tableName)
+		v, err = s.db.GetValue("SELECT COMMENTS FROM "+from, args...)
+	} else {
+		from, args := dictionary("COL_COMMENTS", tableName)
+		v, err = s.db.GetValue("SELECT COMMENTS FROM "+from+" AND COLUMN_NAME = UPPER(?)", append(args, columnName)...)
+	}
+	if err != nil || v == nil || v.IsNil() {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// SetCommentSQL return the COMMENT ON statement of the table, or of its column if columnName isn't blank
+func (s oracle) SetCommentSQL(tableName, columnName, comment string, replace bool) string {
+	comment = "'" + strings.Replace(comment, "'", "''", -1) + "'"
+	if columnName == "" {
+		return fmt.Sprintf("COMMENT ON TABLE %v IS %v", tableName, comment)
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %v.%v IS %v", tableName, columnName, comment)
+}
+
+// Columns return the columns of the table read from the TAB_COLUMNS data dictionary view
+func (s oracle) Columns(tableName string) ([]*automigrate.ColumnInfo, error) {
+	from, args := dictionary("TAB_COLUMNS", tableName)
+	result, err := s.db.GetAll("SELECT COLUMN_NAME, DATA_TYPE, DATA_PRECISION, DATA_SCALE, CHAR_LENGTH, DATA_LENGTH, NULLABLE, DATA_DEFAULT FROM "+from+" ORDER BY COLUMN_ID", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []*automigrate.ColumnInfo
+	for _, record := range result {
+		column := &automigrate.ColumnInfo{
+			Name:     record["COLUMN_NAME"].String(),
+			Type:     record["DATA_TYPE"].String(),
+			Nullable: record["NULLABLE"].String() == "Y",
+		}
+		switch column.Type {
+		case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR":
+			column.Type += fmt.Sprintf("(%d)", record["CHAR_LENGTH"].Int())
+		case "RAW":
+			column.Type += fmt.Sprintf("(%d)", record["DATA_LENGTH"].Int())
+		case "NUMBER":
+			if precision := record["DATA_PRECISION"]; !precision.IsNil() {
+				if scale := record["DATA_SCALE"].Int(); scale != 0 {
+					column.Type += fmt.Sprintf("(%d,%d)", precision.Int(), scale)
+				} else {
+					column.Type += fmt.Sprintf("(%d)", precision.Int())
+				}
+			}
+		default:
+			// TIMESTAMP(6) is the default precision DataTypeOf leaves out
+			column.Type = strings.Replace(column.Type, "TIMESTAMP(6)", "TIMESTAMP", 1)
+		}
+		if value := record["DATA_DEFAULT"]; !value.IsNil() {
+			column.Default.String, column.Default.Valid = strings.TrimSpace(value.String()), true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ModifyColumnType return the type of the field, MODIFY keeps the constraints and default left out
+func (oracle) ModifyColumnType(field *automigrate.StructField, column *automigrate.ColumnInfo, typ string) string {
+	return typ
+}
+
+// SetNullSQL return the statement making the column nullable or not
+func (s oracle) SetNullSQL(tableName, columnName, typ string, nullable bool) []string {
+	constraint := "NOT NULL"
+	if nullable {
+		constraint = "NULL"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %v MODIFY (%v %v)", tableName, columnName, constraint)}
+}
+
+// SetDefaultSQL return the statement changing the default of the column, a NULL default standing for none
+func (s oracle) SetDefaultSQL(tableName, columnName, value string) []string {
+	if value == "" {
+		value = "NULL"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %v MODIFY (%v DEFAULT %v)", tableName, columnName, value)}
+}
+
+// Tables return the tables of the current schema
+func (s oracle) Tables() ([]string, error) {
+	v, err := s.db.GetArray("SELECT TABLE_NAME FROM USER_TABLES")
+	var tables []string
+	for _, tableName := range v {
+		tables = append(tables, tableName.String())
+	}
+	return tables, err
+}
+
+// Indexes return the indexes of the table, except the ones backing the primary key and unique constraints
+func (s oracle) Indexes(tableName string) ([]*automigrate.IndexInfo, error) {
+	query := `SELECT I.INDEX_NAME, C.COLUMN_NAME, I.UNIQUENESS
+	FROM USER_INDEXES I
+		INNER JOIN USER_IND_COLUMNS C ON C.INDEX_NAME = I.INDEX_NAME
+	WHERE I.TABLE_NAME = UPPER(?)
+		AND NOT EXISTS (SELECT 1 FROM USER_CONSTRAINTS K WHERE K.INDEX_NAME = I.INDEX_NAME AND K.CONSTRAINT_TYPE IN ('P', 'U'))
+	ORDER BY I.INDEX_NAME, C.COLUMN_POSITION`
+	args := []interface{}{tableName}
+	if strings.Contains(tableName, ".") {
+		query = `SELECT I.INDEX_NAME, C.COLUMN_NAME, I.UNIQUENESS
+	FROM ALL_INDEXES I
+		INNER JOIN ALL_IND_COLUMNS C ON C.INDEX_OWNER = I.OWNER AND C.INDEX_NAME = I.INDEX_NAME
+	WHERE I.TABLE_OWNER = UPPER(?) AND I.TABLE_NAME = UPPER(?)
+		AND NOT EXISTS (SELECT 1 FROM ALL_CONSTRAINTS K WHERE K.OWNER = I.TABLE_OWNER AND K.INDEX_NAME = I.INDEX_NAME AND K.CONSTRAINT_TYPE IN ('P', 'U'))
+	ORDER BY I.INDEX_NAME, C.COLUMN_POSITION`
+		splitStrings := strings.SplitN(tableName, ".", 2)
+		args = []interface{}{splitStrings[0], splitStrings[1]}
+	}
+
+	result, err := s.db.GetAll(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*automigrate.IndexInfo
+	for _, record := range result {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != record["INDEX_NAME"].String() {
+			indexes = append(indexes, &automigrate.IndexInfo{Name: record["INDEX_NAME"].String(), Unique: record["UNIQUENESS"].String() == "UNIQUE"})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, record["COLUMN_NAME"].String())
+	}
+	return indexes, nil
+}
+
+// Checks return the check constraints of the table, leaving out the NOT NULL ones oracle keeps as checks too.
+// Their conditions are read from SEARCH_CONDITION_VC since oracle 12.2, and from the LONG SEARCH_CONDITION before.
+func (s oracle) Checks(tableName string) (map[string]string, error) {
+	column := "SEARCH_CONDITION"
+	if s.hasSearchConditionVC() {
+		column = "SEARCH_CONDITION_VC"
+	}
+
+	from, args := dictionary("CONSTRAINTS", tableName)
+	result, err := s.db.GetAll("SELECT CONSTRAINT_NAME, "+column+" AS CHECK_CONDITION FROM "+from+" AND CONSTRAINT_TYPE = 'C' AND GENERATED = 'USER NAME'", args...)
+	checks := map[string]string{}
+	for _, record := range result {
+		checks[record["CONSTRAINT_NAME"].String()] = record["CHECK_CONDITION"].String()
+	}
+	return checks, err
+}
+
+// hasSearchConditionVC check the data dictionary has the SEARCH_CONDITION_VC column, added by oracle 12.2
+func (s oracle) hasSearchConditionVC() bool {
+	return s.catalog.Load("search_condition_vc", func() string {
+		v, err := s.db.GetCount("SELECT count(*) FROM ALL_TAB_COLUMNS WHERE OWNER = 'SYS' AND TABLE_NAME = 'ALL_CONSTRAINTS' AND COLUMN_NAME = 'SEARCH_CONDITION_VC'")
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatBool(v > 0)
+	}) == "true"
+}
+
+// ForeignKeys return the names of the foreign keys of the table
+func (s oracle) ForeignKeys(tableName string) ([]string, error) {
+	from, args := dictionary("CONSTRAINTS", tableName)
+	v, err := s.db.GetArray("SELECT CONSTRAINT_NAME FROM "+from+" AND CONSTRAINT_TYPE = 'R'", args...)
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names, err
+}
+
+// RenameTableSQL return the statement renaming the table, keeping it in its schema
+func (s oracle) RenameTableSQL(oldName, newName string) string {
+	return fmt.Sprintf("ALTER TABLE %v RENAME TO %v", oldName, newName[strings.LastIndex(newName, ".")+1:])
+}
+
+// RenameIndexSQL return the statement renaming the index, which lives in the schema of its table
+func (s oracle) RenameIndexSQL(tableName, oldName, newName string) []string {
+	if strings.Contains(tableName, ".") {
+		oldName = strings.SplitN(tableName, ".", 2)[0] + "." + oldName
+	}
+	return []string{fmt.Sprintf("ALTER INDEX %v RENAME TO %v", oldName, newName)}
+}
+
+// TransactionalDDL return false, as DDL statements commit the transaction implicitly
+func (oracle) TransactionalDDL() bool {
+	return false
+}
+
+func (s oracle) ModifyColumn(tableName string, columnName string, typ string) error {
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %v MODIFY (%v %v)", tableName, columnName, typ))
+	return err
+}
+
+func (s oracle) CurrentDatabase() (name string) {
+	return s.catalog.Load("database", func() string {
+		v, _ := s.db.GetValue("SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL")
+		return v.String()
+	})
+}
+
+func parseInt(value interface{}) (int64, error) {
+	return strconv.ParseInt(fmt.Sprint(value), 0, 0)
+}
+
+func (oracle) LimitAndOffsetSQL(limit, offset interface{}) (sql string, err error) {
+	if offset != nil {
+		if parsedOffset, err := parseInt(offset); err != nil {
+			return "", err
+		} else if parsedOffset >= 0 {
+			sql += fmt.Sprintf(" OFFSET %d ROWS", parsedOffset)
+		}
+	}
+	if limit != nil {
+		if parsedLimit, err := parseInt(limit); err != nil {
+			return "", err
+		} else if parsedLimit >= 0 {
+			sql += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", parsedLimit)
+		}
+	}
+	return
+}
+
+func (oracle) SelectFromDummyTable() string {
+	return "FROM DUAL"
+}
+
+func (oracle) LastInsertIDOutputInterstitial(tableName, columnName string, columns []string) string {
+	return ""
+}
+
+func (oracle) LastInsertIDReturningSuffix(tableName, columnName string) string {
+	return ""
+}
+
+func (oracle) DefaultValueStr() string {
+	return "VALUES (DEFAULT)"
+}
+
+// BuildKeyName returns a valid key name (foreign key, index key) for the given table, field and reference,
+// names longer than MaxIdentifierLength are cut and suffixed with a hash of the full name to keep them unique
+func (s oracle) BuildKeyName(kind, tableName string, fields ...string) string {
+	keyName := s.DefaultForeignKeyNamer.BuildKeyName(kind, tableName, fields...)
+	if len(keyName) <= MaxIdentifierLength {
+		return keyName
+	}
+
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(keyName)))[:8]
+	return keyName[:MaxIdentifierLength-len(hash)-1] + "_" + hash
+}
+
+// NormalizeIndexAndColumn returns argument's index name and column name without doing anything
+func (oracle) NormalizeIndexAndColumn(indexName, columnName string) (string, string) {
+	return indexName, columnName
+}
+
+// dictionary returns the data dictionary view holding the table and the condition selecting it,
+// tables of the current schema are looked up in USER_ views, `owner.table` ones in ALL_ views
+func dictionary(view, tableName string) (string, []interface{}) {
+	if strings.Contains(tableName, ".") {
+		splitStrings := strings.SplitN(tableName, ".", 2)
+		return fmt.Sprintf("ALL_%v WHERE OWNER = UPPER(?) AND TABLE_NAME = UPPER(?)", view), []interface{}{splitStrings[0], splitStrings[1]}
+	}
+	return fmt.Sprintf("USER_%v WHERE TABLE_NAME = UPPER(?)", view), []interface{}{tableName}
+}
@@ -0,0 +1,440 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/database/gdb"
+	"github.com/sanrentai/automigrate"
+)
+
+func init() {
+	automigrate.RegisterDialect("postgres", &postgres{})
+}
+
+type postgres struct {
+	db      gdb.DB
+	catalog *automigrate.CatalogCache
+	automigrate.DefaultForeignKeyNamer
+}
+
+func (postgres) GetName() string {
+	return "postgres"
+}
+
+func (s *postgres) SetDB(db gdb.DB) {
+	s.db = db
+}
+
+// SetCatalogCache set the cache the current database and schema names are read through
+func (s *postgres) SetCatalogCache(cache *automigrate.CatalogCache) {
+	s.catalog = cache
+}
+
+func (postgres) BindVar(i int) string {
+	return fmt.Sprintf("$%v", i)
+}
+
+func (postgres) Quote(key string) string {
+	return fmt.Sprintf(`"%s"`, key)
+}
+
+func (s *postgres) DataTypeOf(field *automigrate.StructField) string {
+	var dataValue, sqlType, size, additionalType = automigrate.ParseFieldStructForDialect(field, s)
+
+	if sqlType == "" {
+		switch dataValue.Kind() {
+		case reflect.Bool:
+			sqlType = "boolean"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uintptr:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "serial"
+			} else {
+				sqlType = "integer"
+			}
+		case reflect.Int64, reflect.Uint32, reflect.Uint64:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "bigserial"
+			} else {
+				sqlType = "bigint"
+			}
+		case reflect.Float32, reflect.Float64:
+			sqlType = "numeric"
+		case reflect.String:
+			if _, ok := field.TagSettingsGet("SIZE"); !ok {
+				size = 0 // if SIZE haven't been set, use `text` as the default type, as there are no performance different
+			}
+
+			if size > 0 && size < 65532 {
+				sqlType = fmt.Sprintf("varchar(%d)", size)
+			} else {
+				sqlType = "text"
+			}
+		case reflect.Struct:
+			if _, ok := dataValue.Interface().(time.Time); ok {
+				sqlType = "timestamptz"
+			}
+		default:
+			if automigrate.IsByteArrayOrSlice(dataValue) {
+				sqlType = "bytea"
+
+				if isUUID(dataValue) {
+					sqlType = "uuid"
+				}
+
+				if isJSON(dataValue) {
+					sqlType = "jsonb"
+				}
+			}
+		}
+	}
+
+	if sqlType == "" {
+		panic(fmt.Sprintf("invalid sql type %s (%s) for postgres", dataValue.Type().Name(), dataValue.Kind().String()))
+	}
+
+	if strings.TrimSpace(additionalType) == "" {
+		return sqlType
+	}
+	return fmt.Sprintf("%v %v", sqlType, additionalType)
+}
+
+func (s postgres) fieldCanAutoIncrement(field *automigrate.StructField) bool {
+	if value, ok := field.TagSettingsGet("AUTO_INCREMENT"); ok {
+		return strings.ToLower(value) != "false"
+	}
+	return field.IsPrimaryKey
+}
+
+func (s postgres) HasIndex(tableName string, indexName string) bool {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM pg_indexes WHERE schemaname = ? AND tablename = ? AND indexname = ?", currentSchema, tableName, indexName)
+	return v > 0
+}
+
+func (s postgres) RemoveIndex(tableName string, indexName string) error {
+	if strings.Contains(tableName, ".") {
+		indexName = strings.SplitN(tableName, ".", 2)[0] + "." + indexName
+	}
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v", indexName))
+	return err
+}
+
+func (s postgres) HasForeignKey(tableName string, foreignKeyName string) bool {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount(`SELECT count(con.conname)
+	FROM pg_constraint con
+		INNER JOIN pg_class rel ON rel.oid = con.conrelid
+		INNER JOIN pg_namespace nsp ON nsp.oid = rel.relnamespace
+	WHERE nsp.nspname = ? AND rel.relname = ? AND con.conname = ? AND con.contype = 'f'`, currentSchema, tableName, foreignKeyName)
+	return v > 0
+}
+
+func (s postgres) HasTable(tableName string) bool {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM INFORMATION_SCHEMA.tables WHERE table_schema = ? AND table_name = ? AND table_type = 'BASE TABLE'", currentSchema, tableName)
+	return v > 0
+}
+
+func (s postgres) HasColumn(tableName string, columnName string) bool {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?", currentSchema, tableName, columnName)
+	return v > 0
+}
+
+// GetComment return the comment of the table, or of its column if columnName isn't blank
+func (s postgres) GetComment(tableName, columnName string) (string, bool) {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	quotedTableName := s.Quote(currentSchema) + "." + s.Quote(tableName)
+
+	var (
+		v   gdb.Value
+		err error
+	)
+	if columnName == "" {
+		v, err = s.db.GetValue("SELECT obj_description(?::regclass, 'pg_class')", quotedTableName)
+	} else {
+		v, err = s.db.GetValue("SELECT col_description(attrelid, attnum) FROM pg_attribute WHERE attrelid = ?::regclass AND attname = ?", quotedTableName, columnName)
+	}
+	if err != nil || v == nil || v.IsNil() {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// SetCommentSQL return the COMMENT ON statement of the table, or of its column if columnName isn't blank
+func (s postgres) SetCommentSQL(tableName, columnName, comment string, replace bool) string {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	quotedTableName := s.Quote(currentSchema) + "." + s.Quote(tableName)
+	if columnName == "" {
+		return fmt.Sprintf("COMMENT ON TABLE %v IS %v", quotedTableName, quoteString(comment))
+	}
+	return fmt.Sprintf("COMMENT ON COLUMN %v.%v IS %v", quotedTableName, s.Quote(columnName), quoteString(comment))
+}
+
+// Columns return the columns of the table read from pg_attribute, typed by format_type
+func (s postgres) Columns(tableName string) ([]*automigrate.ColumnInfo, error) {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	result, err := s.db.GetAll(`SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable, pg_get_expr(d.adbin, d.adrelid) AS column_default
+	FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE a.attrelid = ?::regclass AND a.attnum > 0 AND NOT a.attisdropped
+	ORDER BY a.attnum`, s.Quote(currentSchema)+"."+s.Quote(tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []*automigrate.ColumnInfo
+	for _, record := range result {
+		column := &automigrate.ColumnInfo{
+			Name:     record["name"].String(),
+			Type:     record["type"].String(),
+			Nullable: record["nullable"].Bool(),
+		}
+		if value := record["column_default"]; !value.IsNil() {
+			column.Default.String, column.Default.Valid = value.String(), true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ModifyColumnType return the type of the field, serial types being changed to the integer type they stand for
+func (postgres) ModifyColumnType(field *automigrate.StructField, column *automigrate.ColumnInfo, typ string) string {
+	switch strings.ToLower(typ) {
+	case "serial":
+		return "integer"
+	case "bigserial":
+		return "bigint"
+	}
+	return typ
+}
+
+// SetNullSQL return the statement setting or dropping the NOT NULL constraint of the column
+func (s postgres) SetNullSQL(tableName, columnName, typ string, nullable bool) []string {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	action := "SET NOT NULL"
+	if nullable {
+		action = "DROP NOT NULL"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %v.%v ALTER COLUMN %v %v", s.Quote(currentSchema), s.Quote(tableName), s.Quote(columnName), action)}
+}
+
+// SetDefaultSQL return the statement setting or dropping the default of the column
+func (s postgres) SetDefaultSQL(tableName, columnName, value string) []string {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	action := "DROP DEFAULT"
+	if value != "" {
+		action = "SET DEFAULT " + value
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %v.%v ALTER COLUMN %v %v", s.Quote(currentSchema), s.Quote(tableName), s.Quote(columnName), action)}
+}
+
+// Tables return the tables of the current schema
+func (s postgres) Tables() ([]string, error) {
+	v, err := s.db.GetArray("SELECT table_name FROM INFORMATION_SCHEMA.tables WHERE table_schema = CURRENT_SCHEMA() AND table_type = 'BASE TABLE'")
+	var tables []string
+	for _, tableName := range v {
+		tables = append(tables, tableName.String())
+	}
+	return tables, err
+}
+
+// Indexes return the indexes of the table and the condition of the partial ones, except the ones backing the primary key and constraints
+func (s postgres) Indexes(tableName string) ([]*automigrate.IndexInfo, error) {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	result, err := s.db.GetAll(`SELECT i.relname AS name, a.attname AS column_name, ix.indisunique AS is_unique, pg_get_expr(ix.indpred, ix.indrelid) AS filter
+	FROM pg_index ix
+		INNER JOIN pg_class i ON i.oid = ix.indexrelid
+		INNER JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ANY(ix.indkey)
+	WHERE ix.indrelid = ?::regclass AND NOT ix.indisprimary
+		AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = ix.indexrelid)
+	ORDER BY i.relname, array_position(ix.indkey::int2[], a.attnum)`, s.Quote(currentSchema)+"."+s.Quote(tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*automigrate.IndexInfo
+	for _, record := range result {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != record["name"].String() {
+			indexes = append(indexes, &automigrate.IndexInfo{Name: record["name"].String(), Unique: record["is_unique"].Bool(), Filter: record["filter"].String()})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, record["column_name"].String())
+	}
+	return indexes, nil
+}
+
+// Checks return the check constraints of the table, spelled CHECK (...) by pg_get_constraintdef
+func (s postgres) Checks(tableName string) (map[string]string, error) {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	result, err := s.db.GetAll(`SELECT con.conname AS name, pg_get_constraintdef(con.oid) AS expression
+	FROM pg_constraint con
+	WHERE con.conrelid = ?::regclass AND con.contype = 'c'`, s.Quote(currentSchema)+"."+s.Quote(tableName))
+	checks := map[string]string{}
+	for _, record := range result {
+		checks[record["name"].String()] = record["expression"].String()
+	}
+	return checks, err
+}
+
+// ForeignKeys return the names of the foreign keys of the table
+func (s postgres) ForeignKeys(tableName string) ([]string, error) {
+	currentSchema, tableName := currentSchemaAndTable(&s, tableName)
+	v, err := s.db.GetArray("SELECT con.conname FROM pg_constraint con WHERE con.conrelid = ?::regclass AND con.contype = 'f'", s.Quote(currentSchema)+"."+s.Quote(tableName))
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names, err
+}
+
+// RenameTableSQL return the statement renaming the table, keeping it in its schema
+func (s postgres) RenameTableSQL(oldName, newName string) string {
+	currentSchema, oldName := currentSchemaAndTable(&s, oldName)
+	_, newName = currentSchemaAndTable(&s, newName)
+	return fmt.Sprintf("ALTER TABLE %v.%v RENAME TO %v", s.Quote(currentSchema), s.Quote(oldName), s.Quote(newName))
+}
+
+// RenameIndexSQL return the statement renaming the index, which lives in the schema of its table
+func (s postgres) RenameIndexSQL(tableName, oldName, newName string) []string {
+	currentSchema, _ := currentSchemaAndTable(&s, tableName)
+	return []string{fmt.Sprintf("ALTER INDEX %v.%v RENAME TO %v", s.Quote(currentSchema), oldName, newName)}
+}
+
+// TryLockSQL return the query taking the session advisory lock keyed by the hash of the name, without waiting
+func (postgres) TryLockSQL(name string) string {
+	return fmt.Sprintf("SELECT CASE WHEN pg_try_advisory_lock(hashtext(%v)) THEN 1 ELSE 0 END", quoteString(name))
+}
+
+// UnlockSQL return the statement releasing the session advisory lock keyed by the hash of the name
+func (postgres) UnlockSQL(name string) string {
+	return fmt.Sprintf("SELECT pg_advisory_unlock(hashtext(%v))", quoteString(name))
+}
+
+// TransactionalDDL return true, postgres rolls back DDL statements with the transaction
+func (postgres) TransactionalDDL() bool {
+	return true
+}
+
+func (s postgres) ModifyColumn(tableName string, columnName string, typ string) error {
+	_, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %v ALTER COLUMN %v TYPE %v", tableName, columnName, typ))
+	return err
+}
+
+func (s postgres) CurrentDatabase() (name string) {
+	return s.catalog.Load("database", func() string {
+		v, _ := s.db.GetValue("SELECT CURRENT_DATABASE()")
+		return v.String()
+	})
+}
+
+// CurrentSchema return the first existing schema of the search path
+func (s postgres) CurrentSchema() (name string) {
+	return s.catalog.Load("schema", func() string {
+		v, _ := s.db.GetValue("SELECT CURRENT_SCHEMA()")
+		return v.String()
+	})
+}
+
+func parseInt(value interface{}) (int64, error) {
+	return strconv.ParseInt(fmt.Sprint(value), 0, 0)
+}
+
+func (postgres) LimitAndOffsetSQL(limit, offset interface{}) (sql string, err error) {
+	if limit != nil {
+		if parsedLimit, err := parseInt(limit); err != nil {
+			return "", err
+		} else if parsedLimit >= 0 {
+			sql += fmt.Sprintf(" LIMIT %d", parsedLimit)
+		}
+	}
+	if offset != nil {
+		if parsedOffset, err := parseInt(offset); err != nil {
+			return "", err
+		} else if parsedOffset >= 0 {
+			sql += fmt.Sprintf(" OFFSET %d", parsedOffset)
+		}
+	}
+	return
+}
+
+func (postgres) SelectFromDummyTable() string {
+	return ""
+}
+
+func (postgres) LastInsertIDOutputInterstitial(tableName, columnName string, columns []string) string {
+	return ""
+}
+
+func (postgres) LastInsertIDReturningSuffix(tableName, key string) string {
+	return fmt.Sprintf("RETURNING %v.%v", tableName, key)
+}
+
+func (postgres) DefaultValueStr() string {
+	return "DEFAULT VALUES"
+}
+
+// NormalizeIndexAndColumn returns argument's index name and column name without doing anything
+func (postgres) NormalizeIndexAndColumn(indexName, columnName string) (string, string) {
+	return indexName, columnName
+}
+
+// currentSchemaAndTable splits `schema.table`, tables without a schema are looked up in current_schema()
+func currentSchemaAndTable(dialect *postgres, tableName string) (string, string) {
+	if strings.Contains(tableName, ".") {
+		splitStrings := strings.SplitN(tableName, ".", 2)
+		return splitStrings[0], splitStrings[1]
+	}
+	return dialect.CurrentSchema(), tableName
+}
+
+// quoteString returns str as a string literal
+func quoteString(str string) string {
+	return "'" + strings.Replace(str, "'", "''", -1) + "'"
+}
+
+func isUUID(value reflect.Value) bool {
+	if value.Kind() != reflect.Array || value.Type().Len() != 16 {
+		return false
+	}
+	typename := value.Type().Name()
+	lower := strings.ToLower(typename)
+	return "uuid" == lower || "guid" == lower
+}
+
+func isJSON(value reflect.Value) bool {
+	_, ok := value.Interface().(json.RawMessage)
+	return ok
+}
+
+// Jsonb Postgresql's JSONB data type
+type Jsonb struct {
+	json.RawMessage
+}
+
+// Value get value of Jsonb
+func (j Jsonb) Value() (driver.Value, error) {
+	if len(j.RawMessage) == 0 {
+		return nil, nil
+	}
+	return j.MarshalJSON()
+}
+
+// Scan scan value into Jsonb
+func (j *Jsonb) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+
+	return json.Unmarshal(bytes, j)
+}
@@ -0,0 +1,485 @@
+package sqlite
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/database/gdb"
+	"github.com/sanrentai/automigrate"
+)
+
+var (
+	// indexNameRegexp match the name of the index in its CREATE INDEX statement
+	indexNameRegexp = regexp.MustCompile(`(?i)\bINDEX\s+(?:IF\s+NOT\s+EXISTS\s+)?("[^"]+"|\S+)\s+ON\b`)
+	// indexFilterRegexp match the condition of a partial index in its CREATE INDEX statement
+	indexFilterRegexp = regexp.MustCompile(`(?is)\)\s*WHERE\s+(.*)$`)
+	// primaryKeyRegexp match the PRIMARY KEY constraint of a column definition
+	primaryKeyRegexp = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b`)
+	// tablePrimaryKeyRegexp match the PRIMARY KEY table constraint, named or not
+	tablePrimaryKeyRegexp = regexp.MustCompile(`(?is)^(CONSTRAINT\s+\S+\s+)?PRIMARY\s+KEY\b`)
+)
+
+func init() {
+	automigrate.RegisterDialect("sqlite3", &sqlite3{})
+}
+
+type sqlite3 struct {
+	db gdb.DB
+	automigrate.DefaultForeignKeyNamer
+}
+
+func (sqlite3) GetName() string {
+	return "sqlite3"
+}
+
+func (s *sqlite3) SetDB(db gdb.DB) {
+	s.db = db
+}
+
+func (sqlite3) BindVar(i int) string {
+	return "$$$" // ?
+}
+
+func (sqlite3) Quote(key string) string {
+	return fmt.Sprintf(`"%s"`, key)
+}
+
+// Get Data Type for Sqlite Dialect
+func (s *sqlite3) DataTypeOf(field *automigrate.StructField) string {
+	var dataValue, sqlType, size, additionalType = automigrate.ParseFieldStructForDialect(field, s)
+
+	if sqlType == "" {
+		switch dataValue.Kind() {
+		case reflect.Bool:
+			sqlType = "bool"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "integer primary key autoincrement"
+			} else {
+				sqlType = "integer"
+			}
+		case reflect.Int64, reflect.Uint64:
+			if s.fieldCanAutoIncrement(field) {
+				field.TagSettingsSet("AUTO_INCREMENT", "AUTO_INCREMENT")
+				sqlType = "integer primary key autoincrement"
+			} else {
+				sqlType = "bigint"
+			}
+		case reflect.Float32, reflect.Float64:
+			sqlType = "real"
+		case reflect.String:
+			if size > 0 && size < 65532 {
+				sqlType = fmt.Sprintf("varchar(%d)", size)
+			} else {
+				sqlType = "text"
+			}
+		case reflect.Struct:
+			if _, ok := dataValue.Interface().(time.Time); ok {
+				sqlType = "datetime"
+			}
+		default:
+			if automigrate.IsByteArrayOrSlice(dataValue) {
+				sqlType = "blob"
+			}
+		}
+	}
+
+	if sqlType == "" {
+		panic(fmt.Sprintf("invalid sql type %s (%s) for sqlite3", dataValue.Type().Name(), dataValue.Kind().String()))
+	}
+
+	if strings.TrimSpace(additionalType) == "" {
+		return sqlType
+	}
+	return fmt.Sprintf("%v %v", sqlType, additionalType)
+}
+
+func (s sqlite3) fieldCanAutoIncrement(field *automigrate.StructField) bool {
+	if value, ok := field.TagSettingsGet("AUTO_INCREMENT"); ok {
+		return strings.ToLower(value) != "false"
+	}
+	return field.IsPrimaryKey
+}
+
+func (s sqlite3) HasIndex(tableName string, indexName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT count(*) FROM %v.sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?", s.Quote(currentDatabase)), tableName, indexName)
+	return v > 0
+}
+
+func (s sqlite3) RemoveIndex(tableName string, indexName string) error {
+	currentDatabase, _ := currentDatabaseAndTable(&s, tableName)
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX %v.%v", s.Quote(currentDatabase), s.Quote(indexName)))
+	return err
+}
+
+func (s sqlite3) HasForeignKey(tableName string, foreignKeyName string) bool {
+	return false
+}
+
+// CanStoreComment return false, sqlite has no comments
+func (sqlite3) CanStoreComment() bool {
+	return false
+}
+
+// CanAddForeignKey return false, sqlite has no ALTER TABLE ... ADD CONSTRAINT
+func (sqlite3) CanAddForeignKey() bool {
+	return false
+}
+
+func (s sqlite3) HasTable(tableName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount(fmt.Sprintf("SELECT count(*) FROM %v.sqlite_master WHERE type = 'table' AND name = ?", s.Quote(currentDatabase)), tableName)
+	return v > 0
+}
+
+func (s sqlite3) HasColumn(tableName string, columnName string) bool {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	v, _ := s.db.GetCount("SELECT count(*) FROM pragma_table_info(?, ?) WHERE name = ?", tableName, currentDatabase, columnName)
+	return v > 0
+}
+
+// Columns return the columns of the table read from pragma_table_info
+func (s sqlite3) Columns(tableName string) ([]*automigrate.ColumnInfo, error) {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	result, err := s.db.GetAll(`SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?, ?) ORDER BY cid`, tableName, currentDatabase)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []*automigrate.ColumnInfo
+	for _, record := range result {
+		column := &automigrate.ColumnInfo{
+			Name:     record["name"].String(),
+			Type:     record["type"].String(),
+			Nullable: !record["notnull"].Bool(),
+		}
+		if value := record["dflt_value"]; !value.IsNil() {
+			column.Default.String, column.Default.Valid = value.String(), true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// ModifyColumnType return the full definition of the field, as the table is rebuilt with it
+func (s *sqlite3) ModifyColumnType(field *automigrate.StructField, column *automigrate.ColumnInfo, typ string) string {
+	return s.DataTypeOf(field)
+}
+
+// Tables return the tables of the main database
+func (s sqlite3) Tables() ([]string, error) {
+	v, err := s.db.GetArray(fmt.Sprintf("SELECT name FROM %v.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%%'", s.Quote(s.CurrentDatabase())))
+	var tables []string
+	for _, tableName := range v {
+		tables = append(tables, tableName.String())
+	}
+	return tables, err
+}
+
+// Indexes return the indexes of the table created by CREATE INDEX, and the condition of the partial ones
+func (s sqlite3) Indexes(tableName string) ([]*automigrate.IndexInfo, error) {
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	result, err := s.db.GetAll(fmt.Sprintf(`SELECT l.name AS name, i.name AS column_name, l."unique" AS is_unique, m.sql AS definition
+	FROM pragma_index_list(?, ?) AS l, pragma_index_info(l.name, ?) AS i
+		INNER JOIN %v.sqlite_master AS m ON m.type = 'index' AND m.name = l.name
+	WHERE l.origin = 'c'
+	ORDER BY l.name, i.seqno`, s.Quote(currentDatabase)), tableName, currentDatabase, currentDatabase)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*automigrate.IndexInfo
+	for _, record := range result {
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != record["name"].String() {
+			index := &automigrate.IndexInfo{Name: record["name"].String(), Unique: record["is_unique"].Bool()}
+			// sqlite only keeps the condition of partial indexes in their definition
+			if submatch := indexFilterRegexp.FindStringSubmatch(record["definition"].String()); submatch != nil {
+				index.Filter = strings.TrimSpace(submatch[1])
+			}
+			indexes = append(indexes, index)
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, record["column_name"].String())
+	}
+	return indexes, nil
+}
+
+// RenameTableSQL return the statement renaming the table, keeping it in its database
+func (s sqlite3) RenameTableSQL(oldName, newName string) string {
+	currentDatabase, oldName := currentDatabaseAndTable(&s, oldName)
+	_, newName = currentDatabaseAndTable(&s, newName)
+	return fmt.Sprintf("ALTER TABLE %v.%v RENAME TO %v", s.Quote(currentDatabase), s.Quote(oldName), s.Quote(newName))
+}
+
+// RenameIndexSQL return the statements dropping the index and creating it again under its new name,
+// as sqlite can't rename indexes
+func (s sqlite3) RenameIndexSQL(tableName, oldName, newName string) []string {
+	currentDatabase, _ := currentDatabaseAndTable(&s, tableName)
+	v, err := s.db.GetValue(fmt.Sprintf("SELECT sql FROM %v.sqlite_master WHERE type = 'index' AND name = ?", s.Quote(currentDatabase)), oldName)
+	if err != nil || v.IsEmpty() {
+		return nil
+	}
+
+	// the definition reads CREATE [UNIQUE] INDEX name ON table (...), the table being already renamed
+	definition := v.String()
+	loc := indexNameRegexp.FindStringSubmatchIndex(definition)
+	if loc == nil {
+		return nil
+	}
+	definition = definition[:loc[2]] + s.Quote(currentDatabase) + "." + s.Quote(newName) + definition[loc[3]:]
+	return []string{fmt.Sprintf("DROP INDEX %v.%v", s.Quote(currentDatabase), s.Quote(oldName)), definition}
+}
+
+// TransactionalDDL return true, sqlite rolls back DDL statements with the transaction
+func (sqlite3) TransactionalDDL() bool {
+	return true
+}
+
+// ModifyColumn rebuilds the table, as sqlite can't alter a column's type:
+// the rows are copied into a new table defined like the table's CREATE TABLE statement,
+// with the definition of the modified column replaced, so that its other columns and constraints are kept.
+// The old table is dropped, the new one takes its name and the indexes and triggers
+// are created again, all in one transaction, or in the one the migration runs in.
+func (s sqlite3) ModifyColumn(tableName string, columnName string, typ string) error {
+	tableName, columnName = unquote(tableName), unquote(columnName)
+	currentDatabase, tableName := currentDatabaseAndTable(&s, tableName)
+	quotedDatabase := s.Quote(currentDatabase)
+
+	createSQL, err := s.db.GetValue(fmt.Sprintf("SELECT sql FROM %v.sqlite_master WHERE type = 'table' AND name = ?", quotedDatabase), tableName)
+	if err != nil {
+		return err
+	}
+	if createSQL.IsNil() {
+		return fmt.Errorf("table %v not found", tableName)
+	}
+
+	definitions, options, err := splitTableDefinitions(createSQL.String())
+	if err != nil {
+		return fmt.Errorf("can't read the definition of table %v: %v", tableName, err)
+	}
+
+	var (
+		kept                   []string
+		primaryKeyInColumnType = primaryKeyRegexp.MatchString(typ)
+		modified               bool
+	)
+	for _, definition := range definitions {
+		name, rest := definitionName(definition)
+		if isTableConstraint(name) {
+			// the modified column declaring the primary key itself, the table's one would declare it twice
+			if primaryKeyInColumnType && tablePrimaryKeyRegexp.MatchString(definition) {
+				continue
+			}
+		} else if strings.EqualFold(name, columnName) {
+			modified = true
+			definition = s.Quote(columnName) + " " + typ
+			// keep the primary key declared by the column, the field's type only declaring the autoincremented ones
+			if primaryKeyRegexp.MatchString(rest) && !primaryKeyInColumnType {
+				definition += " PRIMARY KEY"
+			}
+		}
+		kept = append(kept, definition)
+	}
+
+	if !modified {
+		return fmt.Errorf("column %v not found in table %v", columnName, tableName)
+	}
+
+	columns, err := s.db.GetArray(`SELECT name FROM pragma_table_info(?, ?) ORDER BY cid`, tableName, currentDatabase)
+	if err != nil {
+		return err
+	}
+
+	// indexes backing constraints have no sql, the CREATE TABLE statement creates them again
+	indexes, err := s.db.GetArray(fmt.Sprintf("SELECT sql FROM %v.sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", quotedDatabase), tableName)
+	if err != nil {
+		return err
+	}
+
+	triggers, err := s.db.GetArray(fmt.Sprintf("SELECT sql FROM %v.sqlite_master WHERE type = 'trigger' AND tbl_name = ?", quotedDatabase), tableName)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, column := range columns {
+		names = append(names, s.Quote(column.String()))
+	}
+
+	var (
+		quotedTableName = quotedDatabase + "." + s.Quote(tableName)
+		tempTableName   = tableName + "__automigrate_rebuild"
+		quotedColumns   = strings.Join(names, ",")
+	)
+
+	sqls := []string{
+		"PRAGMA defer_foreign_keys = ON",
+		fmt.Sprintf("CREATE TABLE %v.%v (%v)%v", quotedDatabase, s.Quote(tempTableName), strings.Join(kept, ", "), options),
+		fmt.Sprintf("INSERT INTO %v.%v (%v) SELECT %v FROM %v", quotedDatabase, s.Quote(tempTableName), quotedColumns, quotedColumns, quotedTableName),
+		fmt.Sprintf("DROP TABLE %v", quotedTableName),
+		fmt.Sprintf("ALTER TABLE %v.%v RENAME TO %v", quotedDatabase, s.Quote(tempTableName), s.Quote(tableName)),
+	}
+	for _, index := range indexes {
+		sqls = append(sqls, index.String())
+	}
+	for _, trigger := range triggers {
+		sqls = append(sqls, trigger.String())
+	}
+
+	return automigrate.Transaction(s.db, func(db gdb.DB) error {
+		for _, sql := range sqls {
+			if _, err := db.Exec(sql); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// splitTableDefinitions split the column definitions and table constraints enclosed in the parentheses of a CREATE TABLE statement,
+// leaving out their comments, and return them with the table options following the parentheses, like WITHOUT ROWID
+func splitTableDefinitions(createSQL string) (definitions []string, options string, err error) {
+	var (
+		depth      int
+		quote      byte
+		definition strings.Builder
+	)
+	for i := 0; i < len(createSQL); i++ {
+		c := createSQL[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case strings.HasPrefix(createSQL[i:], "--"):
+			if end := strings.IndexByte(createSQL[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(createSQL)
+			}
+			c = ' '
+		case strings.HasPrefix(createSQL[i:], "/*"):
+			if end := strings.Index(createSQL[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(createSQL)
+			}
+			c = ' '
+		case c == '(':
+			if depth++; depth == 1 {
+				continue
+			}
+		case c == ',' && depth == 1:
+			definitions = append(definitions, strings.TrimSpace(definition.String()))
+			definition.Reset()
+			continue
+		case c == ')':
+			if depth--; depth == 0 {
+				definitions = append(definitions, strings.TrimSpace(definition.String()))
+				return definitions, createSQL[i+1:], nil
+			}
+		}
+		if depth > 0 {
+			definition.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("unbalanced parentheses in %q", createSQL)
+}
+
+// definitionName return the unquoted first word of a column definition or table constraint, and the rest of it
+func definitionName(definition string) (name, rest string) {
+	if definition == "" {
+		return "", ""
+	}
+
+	closing := map[byte]byte{'"': '"', '`': '`', '\'': '\'', '[': ']'}
+	if end, ok := closing[definition[0]]; ok {
+		if i := strings.IndexByte(definition[1:], end); i >= 0 {
+			return definition[1 : i+1], definition[i+2:]
+		}
+	}
+	if i := strings.IndexAny(definition, " \t\r\n("); i >= 0 {
+		return definition[:i], definition[i:]
+	}
+	return definition, ""
+}
+
+// isTableConstraint check the first word of a definition starts a table constraint rather than a column
+func isTableConstraint(name string) bool {
+	switch strings.ToUpper(name) {
+	case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+		return true
+	}
+	return false
+}
+
+func (s sqlite3) CurrentDatabase() (name string) {
+	return "main"
+}
+
+func parseInt(value interface{}) (int64, error) {
+	return strconv.ParseInt(fmt.Sprint(value), 0, 0)
+}
+
+func (sqlite3) LimitAndOffsetSQL(limit, offset interface{}) (sql string, err error) {
+	if limit != nil {
+		if parsedLimit, err := parseInt(limit); err != nil {
+			return "", err
+		} else if parsedLimit >= 0 {
+			sql += fmt.Sprintf(" LIMIT %d", parsedLimit)
+		}
+	}
+	if offset != nil {
+		if parsedOffset, err := parseInt(offset); err != nil {
+			return "", err
+		} else if parsedOffset >= 0 {
+			sql += fmt.Sprintf(" OFFSET %d", parsedOffset)
+		}
+	}
+	return
+}
+
+func (sqlite3) SelectFromDummyTable() string {
+	return ""
+}
+
+func (sqlite3) LastInsertIDOutputInterstitial(tableName, columnName string, columns []string) string {
+	return ""
+}
+
+func (sqlite3) LastInsertIDReturningSuffix(tableName, columnName string) string {
+	return ""
+}
+
+func (sqlite3) DefaultValueStr() string {
+	return "DEFAULT VALUES"
+}
+
+// NormalizeIndexAndColumn returns argument's index name and column name without doing anything
+func (sqlite3) NormalizeIndexAndColumn(indexName, columnName string) (string, string) {
+	return indexName, columnName
+}
+
+// unquote removes the quotes of a quoted (and possibly schema qualified) name
+func unquote(name string) string {
+	return strings.Replace(name, `"`, "", -1)
+}
+
+// currentDatabaseAndTable splits `schema.table`, tables without a schema live in the `main` database
+func currentDatabaseAndTable(dialect automigrate.Dialect, tableName string) (string, string) {
+	if strings.Contains(tableName, ".") {
+		splitStrings := strings.SplitN(tableName, ".", 2)
+		return splitStrings[0], splitStrings[1]
+	}
+	return dialect.CurrentDatabase(), tableName
+}
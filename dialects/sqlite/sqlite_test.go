@@ -0,0 +1,209 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/database/gdb"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sanrentai/automigrate"
+)
+
+func TestSplitTableDefinitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		createSQL   string
+		definitions []string
+		options     string
+	}{
+		{
+			name:        "columns",
+			createSQL:   `CREATE TABLE "users" ("id" integer primary key autoincrement,"name" varchar(255) )`,
+			definitions: []string{`"id" integer primary key autoincrement`, `"name" varchar(255)`},
+		},
+		{
+			name:        "nested parentheses",
+			createSQL:   `CREATE TABLE t (price numeric(10, 2) CHECK (price >= (0)), CONSTRAINT fk FOREIGN KEY (a, b) REFERENCES p(a, b))`,
+			definitions: []string{`price numeric(10, 2) CHECK (price >= (0))`, `CONSTRAINT fk FOREIGN KEY (a, b) REFERENCES p(a, b)`},
+		},
+		{
+			name:        "quotes",
+			createSQL:   "CREATE TABLE t ([a,b] int, `c)` int, \"d(\" text DEFAULT 'x,)y')",
+			definitions: []string{"[a,b] int", "`c)` int", `"d(" text DEFAULT 'x,)y'`},
+		},
+		{
+			name:        "comments",
+			createSQL:   "CREATE TABLE t (\n\ta int, -- the a, (not b)\n\tb int /* the b, ) */\n)",
+			definitions: []string{"a int", "b int"},
+		},
+		{
+			name:        "table options",
+			createSQL:   "CREATE TABLE t (a int PRIMARY KEY, b text) WITHOUT ROWID",
+			definitions: []string{"a int PRIMARY KEY", "b text"},
+			options:     " WITHOUT ROWID",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			definitions, options, err := splitTableDefinitions(tt.createSQL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(definitions, tt.definitions) || options != tt.options {
+				t.Errorf("splitTableDefinitions = %q %q, want %q %q", definitions, options, tt.definitions, tt.options)
+			}
+		})
+	}
+
+	if _, _, err := splitTableDefinitions("CREATE TABLE t (a int"); err == nil {
+		t.Error("splitTableDefinitions of unbalanced parentheses should fail")
+	}
+}
+
+type rebuildCategory struct {
+	ID uint
+}
+
+type rebuildProduct struct {
+	ID         uint
+	Name       string `automigrate:"size:200;comment:name of the product"`
+	Price      int
+	CategoryID uint
+}
+
+func TestModifyColumn(t *testing.T) {
+	tests := []struct {
+		name      string
+		createSQL string
+		// want are the parts of the definition of the rebuilt table
+		want []string
+	}{
+		{
+			name: "constraints",
+			createSQL: `CREATE TABLE rebuild_product (
+				"id" integer primary key autoincrement,
+				"name" varchar(100) COLLATE NOCASE,
+				"price" integer CHECK (price >= 0),
+				"category_id" integer REFERENCES rebuild_category(id) ON DELETE CASCADE,
+				"code" varchar(20) COLLATE NOCASE UNIQUE,
+				CONSTRAINT chk_id CHECK (id > 0)
+			)`,
+			want: []string{`"name" varchar(200)`, `"id" integer primary key autoincrement`, `CHECK (price >= 0)`, `REFERENCES rebuild_category(id) ON DELETE CASCADE`,
+				`"code" varchar(20) COLLATE NOCASE UNIQUE`, `CONSTRAINT chk_id CHECK (id > 0)`},
+		},
+		{
+			name:      "table primary key",
+			createSQL: `CREATE TABLE rebuild_product (id integer NOT NULL, name varchar(100), price integer CHECK (price >= 0), category_id integer, code text, PRIMARY KEY (id))`,
+			want:      []string{`"name" varchar(200)`, `PRIMARY KEY (id)`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			for _, sql := range []string{
+				`CREATE TABLE rebuild_category (id integer PRIMARY KEY)`,
+				tt.createSQL,
+				`CREATE INDEX idx_rebuild_product_price ON rebuild_product(price) WHERE price > 0`,
+				`CREATE TABLE rebuild_log (product_id integer)`,
+				`CREATE TRIGGER trg_rebuild_product AFTER INSERT ON rebuild_product BEGIN INSERT INTO rebuild_log VALUES (new.id); END`,
+				`INSERT INTO rebuild_category (id) VALUES (1)`,
+				`INSERT INTO rebuild_product (id, name, price, category_id, code) VALUES (1, 'apple', 3, 1, 'a')`,
+			} {
+				if _, err := db.Exec(sql); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := adb.AutoMigrate(&rebuildCategory{}, &rebuildProduct{}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			definition, _ := db.GetValue(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'rebuild_product'`)
+			for _, want := range tt.want {
+				if !strings.Contains(definition.String(), want) {
+					t.Errorf("definition %v should contain %v", definition, want)
+				}
+			}
+			if strings.Contains(definition.String(), "varchar(100)") {
+				t.Errorf("definition %v should have the column modified", definition)
+			}
+
+			for _, name := range []string{"idx_rebuild_product_price", "trg_rebuild_product"} {
+				if n, _ := db.GetCount(`SELECT count(*) FROM sqlite_master WHERE name = ?`, name); n != 1 {
+					t.Errorf("%v should be created again", name)
+				}
+			}
+			if record, _ := db.GetOne(`SELECT name, price, code FROM rebuild_product WHERE id = 1`); record["name"].String() != "apple" || record["price"].Int() != 3 || record["code"].String() != "a" {
+				t.Errorf("row = %v, want it kept", record)
+			}
+			if _, err := db.Exec(`INSERT INTO rebuild_product (id, name, price, category_id, code) VALUES (2, 'pear', 1, 1, 'b')`); err != nil {
+				t.Fatal(err)
+			}
+			if n, _ := db.GetCount(`SELECT count(*) FROM rebuild_log`); n != 2 {
+				t.Errorf("trigger ran %v times, want 2", n)
+			}
+			if _, err := db.Exec(`INSERT INTO rebuild_product (id, name, price, code) VALUES (3, 'plum', -1, 'c')`); err == nil {
+				t.Error("the check of price should be kept")
+			}
+		})
+	}
+}
+
+func TestCreateTableIgnoresComments(t *testing.T) {
+	adb, db := openSQLite(t)
+	if err := adb.AutoMigrate(&rebuildProduct{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if definition, _ := db.GetValue(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'rebuild_product'`); strings.Contains(definition.String(), "COMMENT") {
+		t.Errorf("definition %v shouldn't contain the comment", definition)
+	}
+}
+
+func TestModifyColumnRecorded(t *testing.T) {
+	adb, db := openSQLite(t)
+	if _, err := db.Exec(`CREATE TABLE rebuild_product (id integer PRIMARY KEY, name varchar(100), price integer, category_id integer)`); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := adb.Migrator(&automigrate.Migration{ID: "1", Up: func(db *automigrate.DB) error {
+		return db.AutoMigrate(&rebuildProduct{}).Error
+	}})
+	if err := migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	steps, _ := db.GetValue(`SELECT steps FROM schema_migrations WHERE id = '1'`)
+	for _, want := range []string{`"Kind":"modify_column"`, `CREATE TABLE`, `DROP TABLE`, `RENAME TO`} {
+		if !strings.Contains(steps.String(), want) {
+			t.Errorf("steps %v should contain %v", steps, want)
+		}
+	}
+	if err := migrator.RollbackLast(); err == nil {
+		t.Error("rolling back a modified column should fail")
+	}
+}
+
+// openSQLite return a DB migrating a sqlite database of its own, in a file removed once the test is done
+func openSQLite(t *testing.T) (*automigrate.DB, gdb.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a group of its own, tests run again with -count adding nodes to the same group otherwise
+	group := path
+	gdb.AddConfigNode(group, gdb.ConfigNode{Type: "sqlite", LinkInfo: path})
+	db, err := gdb.New(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adb, err := automigrate.Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adb, db
+}
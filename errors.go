@@ -16,6 +16,12 @@ var (
 	ErrCantStartTransaction = errors.New("can't start transaction")
 	// ErrUnaddressable unaddressable value
 	ErrUnaddressable = errors.New("using unaddressable value")
+	// ErrUnsupportedDialect occurs when no dialect is registered for the database type
+	ErrUnsupportedDialect = errors.New("unsupported dialect")
+	// ErrNarrowingColumn occurs when changing a column's type may lose data, and `automigrate:allow_narrowing` isn't set
+	ErrNarrowingColumn = errors.New("column type change may lose data")
+	// ErrLockTimeout occurs when another instance holds the migration lock for longer than `automigrate:lock_timeout`
+	ErrLockTimeout = errors.New("timeout waiting for the migration lock")
 )
 
 // Errors contains all happened errors
@@ -62,6 +68,16 @@ func (errs Errors) Add(newErrors ...error) Errors {
 	return errs
 }
 
+// Is check one of the errors is target, so that errors.Is sees through them
+func (errs Errors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // Error takes a slice of all errors that have occurred and returns it as a formatted string
 func (errs Errors) Error() string {
 	var errors = []string{}
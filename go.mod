@@ -4,4 +4,5 @@ go 1.14
 
 require (
 	github.com/gogf/gf v1.13.1
+	github.com/mattn/go-sqlite3 v1.14.6
 )
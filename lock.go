@@ -0,0 +1,167 @@
+package automigrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// lockName is the name of the lock migrations take
+	lockName = "automigrate"
+	// defaultLockTimeout is how long migrations wait for the lock, unless `automigrate:lock_timeout` is set
+	defaultLockTimeout = time.Minute
+	// defaultLockTTL is how long the row of the lock table holds the lock, unless `automigrate:lock_ttl` is set
+	defaultLockTTL = time.Hour
+)
+
+// lockRetryInterval is how long migrations wait before trying again to take the lock held by another instance
+var lockRetryInterval = 500 * time.Millisecond
+
+// migrationLock is a row of the automigrate_locks table, locking migrations for dialects without a lock primitive
+type migrationLock struct {
+	Name     string `automigrate:"primary_key;size:191"`
+	LockedAt time.Time
+}
+
+// TableName return the name of the lock table
+func (migrationLock) TableName() string {
+	return "automigrate_locks"
+}
+
+// lock take the migration lock if `automigrate:lock` is set, so that instances migrating the same database at once wait for each other.
+// It returns the DB holding the lock and the function releasing it, which must be called whether the migration failed or not.
+func (s *DB) lock() (*DB, func() error, error) {
+	unlock := func() error { return nil }
+	if enabled, ok := s.Get("automigrate:lock"); !ok || enabled != true || s.db.GetDryRun() {
+		return s, unlock, nil
+	}
+	if held, ok := s.Get("automigrate:lock_held"); ok && held == true {
+		return s, unlock, nil
+	}
+
+	timeout := s.duration("automigrate:lock_timeout", defaultLockTimeout)
+
+	var err error
+	if dialect, ok := s.dialect.(lockDialect); ok {
+		unlock, err = s.lockSession(dialect, timeout)
+	} else {
+		unlock, err = s.lockTable(timeout, s.duration("automigrate:lock_ttl", defaultLockTTL))
+	}
+	if err != nil {
+		return s, nil, err
+	}
+	return s.Set("automigrate:lock_held", true), unlock, nil
+}
+
+// duration return the duration set by the setting, or else the default one
+func (s *DB) duration(name string, defaultDuration time.Duration) time.Duration {
+	if value, ok := s.Get(name); ok {
+		if duration, ok := value.(time.Duration); ok {
+			return duration
+		}
+	}
+	return defaultDuration
+}
+
+// lockSession take the lock of the dialect on a connection of its own, as it's held by the session that took it
+func (s *DB) lockSession(dialect lockDialect, timeout time.Duration) (func() error, error) {
+	master, err := s.db.Master()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := contextOf(s.db)
+	conn, err := master.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = retryLock(ctx, timeout, func() (bool, error) {
+		var taken int
+		err := conn.QueryRowContext(ctx, dialect.TryLockSQL(lockName)).Scan(&taken)
+		return taken > 0, err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer conn.Close()
+		// the lock is released even when the migration was interrupted by its context
+		_, err := conn.ExecContext(context.Background(), dialect.UnlockSQL(lockName))
+		return err
+	}, nil
+}
+
+// lockTable take the lock by inserting its row into the lock table, which fails while another instance holds it.
+// The row of an instance that stopped without releasing the lock is taken over once older than ttl,
+// which must be longer than migrations take and than the clocks of the instances differ.
+func (s *DB) lockTable(timeout, ttl time.Duration) (func() error, error) {
+	scope := s.NewScope(&migrationLock{})
+	// instances starting at once race into creating the table, only one of them succeeds
+	if err := s.migrateOwnTable(&migrationLock{}); err != nil && !s.dialect.HasTable(scope.TableName()) {
+		return nil, err
+	}
+
+	var (
+		insertSQL = fmt.Sprintf("INSERT INTO %v (%v, %v) VALUES (?, ?)", scope.QuotedTableName(), scope.Quote("name"), scope.Quote("locked_at"))
+		staleSQL  = fmt.Sprintf("DELETE FROM %v WHERE %v = ? AND %v < ?", scope.QuotedTableName(), scope.Quote("name"), scope.Quote("locked_at"))
+		countSQL  = fmt.Sprintf("SELECT count(*) FROM %v WHERE %v = ?", scope.QuotedTableName(), scope.Quote("name"))
+		released  bool
+	)
+	err := retryLock(contextOf(s.db), timeout, func() (bool, error) {
+		_, err := s.db.Exec(insertSQL, lockName, time.Now())
+		if err == nil {
+			return true, nil
+		}
+
+		if result, staleErr := s.db.Exec(staleSQL, lockName, time.Now().Add(-ttl)); staleErr != nil {
+			return false, staleErr
+		} else if n, _ := result.RowsAffected(); n > 0 {
+			defaultLogger.Print("warning", fmt.Sprintf("[warning] taking over the migration lock held for more than %v", ttl))
+			_, err = s.db.Exec(insertSQL, lockName, time.Now())
+			return err == nil, nil
+		}
+
+		// with no row holding the lock the insert failed for another reason, unless the lock was released in between,
+		// so the error is returned when that happens twice in a row
+		count, countErr := s.db.GetCount(countSQL, lockName)
+		if countErr != nil {
+			return false, countErr
+		}
+		if count == 0 && released {
+			return false, err
+		}
+		released = count == 0
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		_, err := withLink(s.db, context.Background(), nil).Exec(fmt.Sprintf("DELETE FROM %v WHERE %v = ?", scope.QuotedTableName(), scope.Quote("name")), lockName)
+		return err
+	}, nil
+}
+
+// retryLock call tryLock until it takes the lock, fails, the timeout expires or the context is done
+func retryLock(ctx context.Context, timeout time.Duration, tryLock func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		taken, err := tryLock()
+		if err != nil || taken {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w after %v", ErrLockTimeout, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w while waiting for the migration lock", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
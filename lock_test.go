@@ -0,0 +1,72 @@
+package automigrate_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sanrentai/automigrate"
+)
+
+type lockModel struct {
+	ID uint
+}
+
+func TestLockTable(t *testing.T) {
+	tests := []struct {
+		name string
+		// lockedFor is how long another instance holds the lock, none if zero
+		lockedFor time.Duration
+		ttl       time.Duration
+		wantErr   error
+	}{
+		{name: "free"},
+		{name: "held", lockedFor: time.Minute, ttl: time.Hour, wantErr: automigrate.ErrLockTimeout},
+		{name: "stale", lockedFor: 2 * time.Hour, ttl: time.Hour},
+		{name: "held within ttl", lockedFor: 2 * time.Hour, ttl: 3 * time.Hour, wantErr: automigrate.ErrLockTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			adb = adb.Set("automigrate:lock", true).Set("automigrate:lock_timeout", 100*time.Millisecond)
+			if tt.ttl > 0 {
+				adb = adb.Set("automigrate:lock_ttl", tt.ttl)
+			}
+			// the first migration creates the lock table
+			if err := adb.AutoMigrate().Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.lockedFor > 0 {
+				if _, err := db.Exec("INSERT INTO automigrate_locks (name, locked_at) VALUES (?, ?)", "automigrate", time.Now().Add(-tt.lockedFor)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := adb.AutoMigrate(&lockModel{}).Error
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AutoMigrate error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				if n, _ := db.GetCount("SELECT count(*) FROM automigrate_locks"); n != 0 {
+					t.Errorf("%v locks left, want the lock released", n)
+				}
+			}
+		})
+	}
+}
+
+func TestLockReleased(t *testing.T) {
+	adb, db := openSQLite(t)
+	migrated := adb.Set("automigrate:lock", true).Set("automigrate:lock_timeout", 100*time.Millisecond).AutoMigrate(&lockModel{})
+	if err := migrated.Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// another instance takes the lock, the DB returned by the first migration must wait for it
+	if _, err := db.Exec("INSERT INTO automigrate_locks (name, locked_at) VALUES (?, ?)", "automigrate", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrated.AutoMigrate(&lockModel{}).Error; !errors.Is(err, automigrate.ErrLockTimeout) {
+		t.Errorf("AutoMigrate error = %v, want %v", err, automigrate.ErrLockTimeout)
+	}
+}
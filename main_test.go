@@ -0,0 +1,62 @@
+package automigrate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gogf/gf/database/gdb"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sanrentai/automigrate"
+	_ "github.com/sanrentai/automigrate/dialects/sqlite"
+)
+
+// openSQLite return a DB migrating a sqlite database of its own, in a file removed once the test is done
+func openSQLite(t *testing.T) (*automigrate.DB, gdb.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a group of its own, tests run again with -count adding nodes to the same group otherwise
+	group := path
+	gdb.AddConfigNode(group, gdb.ConfigNode{Type: "sqlite", LinkInfo: path})
+	db, err := gdb.New(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adb, err := automigrate.Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return adb, db
+}
+
+// tables return the tables of the sqlite database
+func tables(t *testing.T, db gdb.DB) []string {
+	t.Helper()
+	v, err := db.GetArray("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names
+}
+
+// columns return the columns of the sqlite table
+func columns(t *testing.T, db gdb.DB, tableName string) []string {
+	t.Helper()
+	v, err := db.GetArray("SELECT name FROM pragma_table_info(?) ORDER BY cid", tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, name := range v {
+		names = append(names, name.String())
+	}
+	return names
+}
@@ -0,0 +1,330 @@
+package automigrate
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Migration is a change applied exactly once, like a data fix or one-off DDL, recorded by its ID in the schema_migrations table
+type Migration struct {
+	ID   string
+	Up   func(*DB) error
+	Down func(*DB) error
+}
+
+// SchemaMigration is a row of the schema_migrations table, recording an applied migration
+type SchemaMigration struct {
+	ID        string `automigrate:"primary_key;size:191"`
+	Duration  int64  // milliseconds Up took
+	AppliedAt time.Time
+	Steps     string `automigrate:"size:65535"` // statements Up executed, as JSON, reversed by rollbacks of migrations without Down function
+}
+
+// TableName return the name of the migrations history table
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var (
+	registeredMigrations []*Migration
+	migrationsLock       sync.Mutex
+)
+
+// RegisterMigration register migrations to run after the ones already registered, usually from init functions:
+//
+//	automigrate.RegisterMigration(&automigrate.Migration{
+//		ID: "20200801_fill_user_names",
+//		Up: func(db *automigrate.DB) error {
+//			return db.Exec("UPDATE users SET name = email WHERE name IS NULL").Error
+//		},
+//	})
+func RegisterMigration(migrations ...*Migration) {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	registeredMigrations = append(registeredMigrations, migrations...)
+}
+
+// Migrator runs migrations in order, each one once
+type Migrator struct {
+	db         *DB
+	migrations []*Migration
+}
+
+// Migrator return a Migrator running the given migrations, or the registered ones if none is given
+func (s *DB) Migrator(migrations ...*Migration) *Migrator {
+	if len(migrations) == 0 {
+		migrationsLock.Lock()
+		migrations = append(migrations, registeredMigrations...)
+		migrationsLock.Unlock()
+	}
+	db := s.Unscoped()
+	// the errors of the migrator are its own
+	db.Error = nil
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Migrate run the migrations missing from the schema_migrations table in order, stopping at the first failing one
+func (m *Migrator) Migrate() error {
+	return m.locked((*Migrator).migrate)
+}
+
+func (m *Migrator) migrate() error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	records, err := m.history()
+	if err != nil {
+		return err
+	}
+
+	applied := map[string]bool{}
+	for _, record := range records {
+		applied[record.ID] = true
+	}
+
+	for _, migration := range m.migrations {
+		if applied[migration.ID] {
+			continue
+		}
+		if err := m.up(migration); err != nil {
+			return fmt.Errorf("migration %v: %w", migration.ID, err)
+		}
+	}
+	return nil
+}
+
+// locked run fn with the migrator holding the migration lock if `automigrate:lock` is set, releasing it whatever fn returns
+func (m *Migrator) locked(fn func(*Migrator) error) (err error) {
+	db, unlock, err := m.db.lock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return fn(&Migrator{db: db, migrations: m.migrations})
+}
+
+// validate check every migration has an ID of its own and an Up function
+func (m *Migrator) validate() error {
+	ids := map[string]bool{}
+	for _, migration := range m.migrations {
+		switch {
+		case migration.ID == "":
+			return errors.New("migration without ID")
+		case ids[migration.ID]:
+			return fmt.Errorf("migration %v registered twice", migration.ID)
+		case migration.Up == nil:
+			return fmt.Errorf("migration %v has no Up function", migration.ID)
+		}
+		ids[migration.ID] = true
+	}
+	return nil
+}
+
+// history create the schema_migrations table if missing, and return the migrations it records in the order they were applied.
+// The table is migrated alone, pruning would drop the tables of all the models.
+func (m *Migrator) history() ([]*SchemaMigration, error) {
+	if err := m.db.migrateOwnTable(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []*SchemaMigration
+	scope := m.db.NewScope(&SchemaMigration{})
+	err := m.db.db.GetStructs(&records, fmt.Sprintf("SELECT * FROM %v ORDER BY %v, %v", scope.QuotedTableName(), scope.Quote("applied_at"), scope.Quote("id")))
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return records, err
+}
+
+// up run the migration, in a transaction if the dialect's DDL is transactional, then record it as applied along with the statements it executed
+func (m *Migrator) up(migration *Migration) error {
+	var (
+		plan  = &Plan{}
+		start = time.Now()
+	)
+	db := m.db.withPlan(plan, false).transaction(func(db *DB) *DB {
+		err := migration.Up(db)
+		if err == nil {
+			err = m.record(db, migration.ID, plan, start)
+		}
+		db.AddError(err)
+		return db
+	})
+	return db.Error
+}
+
+// record insert the applied migration into the schema_migrations table
+func (m *Migrator) record(db *DB, id string, plan *Plan, start time.Time) error {
+	steps, err := json.Marshal(plan.Steps)
+	if err != nil {
+		return err
+	}
+
+	record := &SchemaMigration{ID: id, Duration: time.Since(start).Milliseconds(), AppliedAt: start, Steps: string(steps)}
+	scope := db.NewScope(record)
+	_, err = db.db.Exec(fmt.Sprintf("INSERT INTO %v (%v, %v, %v, %v) VALUES (?, ?, ?, ?)", scope.QuotedTableName(), scope.Quote("id"), scope.Quote("duration"), scope.Quote("applied_at"), scope.Quote("steps")),
+		record.ID, record.Duration, record.AppliedAt, record.Steps)
+	return err
+}
+
+// RollbackLast roll back the last applied migration
+func (m *Migrator) RollbackLast() error {
+	return m.locked((*Migrator).rollbackLast)
+}
+
+func (m *Migrator) rollbackLast() error {
+	records, err := m.history()
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	return m.down(records[len(records)-1])
+}
+
+// RollbackTo roll back the migrations applied after the one with the given ID, latest first, keeping that one applied
+func (m *Migrator) RollbackTo(id string) error {
+	return m.locked(func(m *Migrator) error {
+		return m.rollbackTo(id)
+	})
+}
+
+func (m *Migrator) rollbackTo(id string) error {
+	records, err := m.history()
+	if err != nil {
+		return err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].ID == id {
+			for j := len(records) - 1; j > i; j-- {
+				if err := m.down(records[j]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("migration %v not applied", id)
+}
+
+// Redo roll back the last applied migration and apply it again
+func (m *Migrator) Redo() error {
+	return m.locked((*Migrator).redo)
+}
+
+func (m *Migrator) redo() error {
+	records, err := m.history()
+	if err != nil || len(records) == 0 {
+		return err
+	}
+
+	record := records[len(records)-1]
+	migration := m.migration(record.ID)
+	if migration == nil {
+		return fmt.Errorf("migration %v: not registered, can't be applied again", record.ID)
+	}
+	if err := m.down(record); err != nil {
+		return err
+	}
+	if err := m.up(migration); err != nil {
+		return fmt.Errorf("migration %v: %w", migration.ID, err)
+	}
+	return nil
+}
+
+// migration return the migration with the given ID, nil if there's none
+func (m *Migrator) migration(id string) *Migration {
+	for _, migration := range m.migrations {
+		if migration.ID == id {
+			return migration
+		}
+	}
+	return nil
+}
+
+// down roll back the applied migration with its Down function, or else by reversing the statements recorded when it was applied,
+// then remove it from the history unless rolling back failed. It runs in a transaction if the dialect's DDL is transactional.
+func (m *Migrator) down(record *SchemaMigration) error {
+	db := m.db.transaction(func(db *DB) *DB {
+		var err error
+		if migration := m.migration(record.ID); migration != nil && migration.Down != nil {
+			err = migration.Down(db)
+		} else {
+			err = (&Migrator{db: db, migrations: m.migrations}).reverse(record)
+		}
+		if err == nil {
+			scope := db.NewScope(record)
+			_, err = db.db.Exec(fmt.Sprintf("DELETE FROM %v WHERE %v = ?", scope.QuotedTableName(), scope.Quote("id")), record.ID)
+		}
+		db.AddError(err)
+		return db
+	})
+	if db.Error != nil {
+		return fmt.Errorf("migration %v: %w", record.ID, db.Error)
+	}
+	return nil
+}
+
+// reverse undo the tables, columns, indexes and constraints the migration added, latest first.
+// Nothing is undone if any statement can't be, like data changes, column modifications, created schemas,
+// which may hold other tables, or comments of tables and columns the migration didn't add.
+// Dialects without transactional DDL keep the statements undone before one that fails.
+func (m *Migrator) reverse(record *SchemaMigration) error {
+	var steps []*PlanStep
+	if record.Steps != "" {
+		if err := json.Unmarshal([]byte(record.Steps), &steps); err != nil {
+			return err
+		}
+	}
+
+	added := map[string]bool{}
+	for _, step := range steps {
+		switch step.Kind {
+		case "create_table":
+			added[step.Table] = true
+		case "add_column":
+			added[step.Table+"."+step.Name] = true
+		case "add_index", "add_foreign_key", "add_check":
+		case "comment":
+			// comments are dropped with their table or column, the ones of existing tables and columns can't be restored
+			if !added[step.Table] && (step.Name == "" || !added[step.Table+"."+step.Name]) {
+				return fmt.Errorf("no Down function, and statement %q can't be reversed", step.SQL)
+			}
+		default:
+			return fmt.Errorf("no Down function, and statement %q can't be reversed", step.SQL)
+		}
+	}
+
+	db := m.db
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		scope := db.Table(step.Table).NewScope(nil)
+		reason := fmt.Sprintf("rolling back migration %v", record.ID)
+		switch step.Kind {
+		case "create_table":
+			scope.dropTable(reason)
+		case "add_column":
+			scope.dropColumn(step.Name, reason)
+		case "add_index":
+			scope.dropIndex(step.Name, reason)
+		case "add_foreign_key":
+			scope.describe(step.Table, "drop_constraint", step.Name, reason)
+			scope.Raw(scope.dropForeignKeySQL(step.Table, step.Name)).Exec()
+		case "add_check":
+			scope.describe(step.Table, "drop_constraint", step.Name, reason)
+			scope.Raw(scope.dropCheckSQL(step.Table, step.Name)).Exec()
+		}
+		if db = scope.db; db.Error != nil {
+			return db.Error
+		}
+	}
+	return nil
+}
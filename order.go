@@ -0,0 +1,239 @@
+package automigrate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// modelOrder is the order models are migrated in, the models referenced by a relationship before the ones referencing them
+type modelOrder struct {
+	values []interface{}
+	// deps contains the positions of the models values[i] depends on, which must be migrated before it
+	deps [][]int
+	// foreignKeys contains the fields whose foreign keys are added once values[i] is migrated,
+	// the ones of relationships between models depending on each other are added once all tables exist, at len(values)
+	foreignKeys map[int][]modelField
+	// cycles contains the tables of the models depending on each other, in argument order
+	cycles [][]string
+}
+
+// modelField is a relationship field of a model
+type modelField struct {
+	value interface{}
+	field *StructField
+}
+
+// orderModels sort the models by their belongs to, has one, has many and many to many relationships, keeping argument order otherwise.
+// Models depending on each other are migrated in argument order, and their foreign keys deferred until all tables exist.
+func (s *DB) orderModels(values []interface{}) *modelOrder {
+	var (
+		n          = len(values)
+		tables     = make([]string, n)
+		indexes    = map[reflect.Type]int{}
+		deps       = make([]map[int]bool, n)
+		joinTables = map[string]bool{}
+		relations  []modelRelation
+	)
+	for i, value := range values {
+		scope := s.NewScope(value)
+		tables[i] = scope.TableName()
+		deps[i] = map[int]bool{}
+		if modelType := scope.GetModelStruct().ModelType; modelType != nil {
+			if _, ok := indexes[modelType]; !ok {
+				indexes[modelType] = i
+			}
+		}
+	}
+
+	for i, value := range values {
+		scope := s.NewScope(value)
+		for _, field := range scope.GetModelStruct().StructFields {
+			relationship := field.Relationship
+			if relationship == nil {
+				continue
+			}
+			j, ok := indexes[indirectType(field.Struct.Type)]
+			if !ok {
+				j = -1
+			}
+
+			relation := modelRelation{modelField: modelField{value: value, field: field}, child: i, parent: j}
+			switch relationship.Kind {
+			case "belongs_to":
+			case "has_one", "has_many":
+				relation.child, relation.parent = j, i
+			case "many_to_many":
+				// a join table declared by both models would make them depend on each other, only its first declaration counts
+				if joinTable := relationship.JoinTableHandler.Table(s); !joinTables[joinTable] {
+					joinTables[joinTable] = true
+					if j >= 0 && j != i {
+						deps[i][j] = true
+					}
+				}
+				continue
+			default:
+				continue
+			}
+
+			if relation.child >= 0 && relation.parent >= 0 && relation.child != relation.parent {
+				deps[relation.child][relation.parent] = true
+			}
+			if _, ok := field.TagSettingsGet("CONSTRAINT"); ok {
+				relations = append(relations, relation)
+			}
+		}
+	}
+
+	components := stronglyConnected(deps)
+	order := &modelOrder{foreignKeys: map[int][]modelField{}}
+	positions := make([]int, n)
+	placed := make([]bool, n)
+	// last placed model of each component, models depending on each other are migrated one after another
+	previous := map[int]int{}
+	for len(order.values) < n {
+		for i := 0; i < n; i++ {
+			if placed[i] || !ready(i, deps, placed, components) {
+				continue
+			}
+			var before []int
+			for dep := range deps[i] {
+				if components[dep] != components[i] {
+					before = append(before, positions[dep])
+				}
+			}
+			if last, ok := previous[components[i]]; ok {
+				before = append(before, positions[last])
+			}
+			previous[components[i]] = i
+
+			positions[i] = len(order.values)
+			placed[i] = true
+			order.values = append(order.values, values[i])
+			order.deps = append(order.deps, before)
+			break
+		}
+	}
+
+	members := map[int][]string{}
+	for i := 0; i < n; i++ {
+		members[components[i]] = append(members[components[i]], tables[i])
+	}
+	for i := 0; i < n; i++ {
+		if cycle := members[components[i]]; len(cycle) > 1 {
+			order.cycles = append(order.cycles, cycle)
+			delete(members, components[i])
+		}
+	}
+
+	for _, relation := range relations {
+		var position int
+		switch {
+		case relation.child < 0 || relation.parent < 0:
+			// the other table isn't migrated with the models, it must exist already
+			position = positions[relation.other()]
+		case relation.child != relation.parent && components[relation.child] == components[relation.parent]:
+			position = n
+		case positions[relation.parent] > positions[relation.child]:
+			position = positions[relation.parent]
+		default:
+			position = positions[relation.child]
+		}
+		order.foreignKeys[position] = append(order.foreignKeys[position], relation.modelField)
+	}
+	return order
+}
+
+// modelRelation is a relationship between the model with a foreign key and the one it references, -1 if not migrated with them
+type modelRelation struct {
+	modelField
+	child, parent int
+}
+
+// other return the model of the relationship migrated with the models
+func (r modelRelation) other() int {
+	if r.child < 0 {
+		return r.parent
+	}
+	return r.child
+}
+
+// ready check all the models the component of i depends on are placed, but the ones depending on i too,
+// so that the models depending on each other become ready together and are placed in argument order
+func ready(i int, deps []map[int]bool, placed []bool, components []int) bool {
+	for j := range deps {
+		if components[j] != components[i] {
+			continue
+		}
+		for dep := range deps[j] {
+			if !placed[dep] && components[dep] != components[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stronglyConnected return the strongly connected component of each model, models depending on each other share one
+func stronglyConnected(deps []map[int]bool) []int {
+	var (
+		n          = len(deps)
+		components = make([]int, n)
+		indexes    = make([]int, n)
+		lowLinks   = make([]int, n)
+		onStack    = make([]bool, n)
+		stack      []int
+		index      = 1
+		component  int
+		visit      func(int)
+	)
+	visit = func(v int) {
+		indexes[v], lowLinks[v] = index, index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+		for w := range deps[v] {
+			if indexes[w] == 0 {
+				visit(w)
+				if lowLinks[w] < lowLinks[v] {
+					lowLinks[v] = lowLinks[w]
+				}
+			} else if onStack[w] && indexes[w] < lowLinks[v] {
+				lowLinks[v] = indexes[w]
+			}
+		}
+		if lowLinks[v] == indexes[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				components[w] = component
+				if w == v {
+					break
+				}
+			}
+			component++
+		}
+	}
+	for v := 0; v < n; v++ {
+		if indexes[v] == 0 {
+			visit(v)
+		}
+	}
+	return components
+}
+
+// indirectType return the struct type of a field, through pointers and slices
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
+
+// warnCycles log the models depending on each other, whose foreign keys are added once all tables exist
+func (o *modelOrder) warnCycles() {
+	for _, cycle := range o.cycles {
+		defaultLogger.Print("warning", fmt.Sprintf("[warning] dependency cycle between tables %v, migrated in argument order, their foreign keys are added once all tables exist", strings.Join(cycle, ", ")))
+	}
+}
@@ -0,0 +1,159 @@
+package automigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+type orderCompany struct {
+	ID uint
+}
+
+type orderUser struct {
+	ID        uint
+	CompanyID uint
+	Company   orderCompany `automigrate:"constraint:OnDelete:CASCADE"`
+	ProfileID uint
+	Profile   *orderProfile `automigrate:"foreignkey:ProfileID;constraint"`
+}
+
+type orderProfile struct {
+	ID     uint
+	UserID uint
+	User   *orderUser `automigrate:"foreignkey:UserID;constraint"`
+}
+
+type orderAddress struct {
+	ID     uint
+	UserID uint
+	User   orderUser `automigrate:"constraint"`
+}
+
+type orderPost struct {
+	ID       uint
+	Comments []orderComment `automigrate:"constraint"`
+}
+
+type orderComment struct {
+	ID          uint
+	OrderPostID uint
+}
+
+func TestStronglyConnected(t *testing.T) {
+	tests := []struct {
+		name string
+		deps []map[int]bool
+		// want lists the models sharing a component, the others having one each
+		want [][]int
+	}{
+		{"no dependencies", []map[int]bool{{}, {}, {}}, nil},
+		{"chain", []map[int]bool{{1: true}, {2: true}, {}}, nil},
+		{"self reference", []map[int]bool{{0: true}, {}}, nil},
+		{"two models", []map[int]bool{{1: true}, {0: true}, {}}, [][]int{{0, 1}}},
+		{"three models", []map[int]bool{{1: true}, {2: true}, {0: true}, {0: true}}, [][]int{{0, 1, 2}}},
+		{"two cycles", []map[int]bool{{1: true}, {0: true}, {3: true, 0: true}, {2: true}}, [][]int{{0, 1}, {2, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			components := stronglyConnected(tt.deps)
+			shared := map[int]bool{}
+			for _, members := range tt.want {
+				for _, i := range members {
+					shared[i] = true
+					if components[i] != components[members[0]] {
+						t.Errorf("components %v: %v and %v should share one", components, i, members[0])
+					}
+				}
+			}
+			for i := range components {
+				for j := range components {
+					if i != j && components[i] == components[j] && (!shared[i] || !shared[j]) {
+						t.Errorf("components %v: %v and %v shouldn't share one", components, i, j)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOrderModels(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   []string
+		cycles [][]string
+		// foreignKeys is the position each relationship's foreign key is added at, by model and field
+		foreignKeys map[string]int
+	}{
+		{
+			name:        "argument order, related model not migrated",
+			values:      []interface{}{&orderPost{}, &orderCompany{}},
+			want:        []string{"order_post", "order_company"},
+			foreignKeys: map[string]int{"order_post.Comments": 0},
+		},
+		{
+			name:        "referenced model first",
+			values:      []interface{}{&orderAddress{}, &orderUser{}, &orderCompany{}, &orderProfile{}},
+			want:        []string{"order_company", "order_user", "order_address", "order_profile"},
+			cycles:      [][]string{{"order_user", "order_profile"}},
+			foreignKeys: map[string]int{"order_user.Company": 1, "order_address.User": 2, "order_user.Profile": 4, "order_profile.User": 4},
+		},
+		{
+			name:        "has many",
+			values:      []interface{}{&orderComment{}, &orderPost{}},
+			want:        []string{"order_post", "order_comment"},
+			foreignKeys: map[string]int{"order_post.Comments": 1},
+		},
+		{
+			name:        "cycle in argument order once its dependencies are migrated",
+			values:      []interface{}{&orderUser{}, &orderProfile{}, &orderCompany{}},
+			want:        []string{"order_company", "order_user", "order_profile"},
+			cycles:      [][]string{{"order_user", "order_profile"}},
+			foreignKeys: map[string]int{"order_user.Company": 1, "order_user.Profile": 3, "order_profile.User": 3},
+		},
+		{
+			name:        "cycle in argument order reversed",
+			values:      []interface{}{&orderProfile{}, &orderUser{}, &orderCompany{}},
+			want:        []string{"order_company", "order_profile", "order_user"},
+			cycles:      [][]string{{"order_profile", "order_user"}},
+			foreignKeys: map[string]int{"order_user.Company": 2, "order_user.Profile": 3, "order_profile.User": 3},
+		},
+	}
+
+	db := NewDB("common", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := db.orderModels(tt.values)
+
+			var tables []string
+			for _, value := range order.values {
+				tables = append(tables, db.NewScope(value).TableName())
+			}
+			if !reflect.DeepEqual(tables, tt.want) {
+				t.Errorf("order = %v, want %v", tables, tt.want)
+			}
+			if !reflect.DeepEqual(order.cycles, tt.cycles) {
+				t.Errorf("cycles = %v, want %v", order.cycles, tt.cycles)
+			}
+
+			foreignKeys := map[string]int{}
+			for position, fields := range order.foreignKeys {
+				for _, field := range fields {
+					foreignKeys[db.NewScope(field.value).TableName()+"."+field.field.Name] = position
+				}
+			}
+			if !reflect.DeepEqual(foreignKeys, tt.foreignKeys) {
+				t.Errorf("foreign keys = %v, want %v", foreignKeys, tt.foreignKeys)
+			}
+
+			// every model is migrated after the ones it waits for
+			for i, deps := range order.deps {
+				for _, dep := range deps {
+					if dep >= i {
+						t.Errorf("%v waits for %v, migrated after it", tables[i], tables[dep])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package automigrate
+
+import (
+	"sync"
+)
+
+// workers return how many models `automigrate:workers` lets migrate at once, 1 unless set
+func (s *DB) workers() int {
+	if value, ok := s.Get("automigrate:workers"); ok {
+		if workers, ok := value.(int); ok && workers > 1 {
+			return workers
+		}
+	}
+	return 1
+}
+
+// migrateParallel run the migration of each model with the given number of workers, starting it once the models it depends on are migrated.
+// Each worker runs its statements on a connection of its own, and records them into the plan once its model is migrated,
+// so that the statements of a table aren't mixed with the other tables' ones in the plan. gdb logs them as they run,
+// interleaved with the other workers' ones. The errors of all models are collected in order.
+func (s *DB) migrateParallel(order *modelOrder, workers int, migrate func(db *DB, i int) *DB) *DB {
+	var (
+		n       = len(order.values)
+		done    = make([]chan struct{}, n)
+		results = make([]*DB, n)
+		panics  = make([]interface{}, n)
+		slots   = make(chan struct{}, workers)
+		wg      sync.WaitGroup
+	)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for i := range order.values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			for _, dep := range order.deps[i] {
+				<-done[dep]
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			defer func() {
+				// panics are raised again by the caller's goroutine, as when migrating one model after another
+				if r := recover(); r != nil {
+					panics[i] = r
+				}
+			}()
+
+			db, merge := s.fork()
+			results[i] = migrate(db, i)
+			merge()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range panics {
+		if r != nil {
+			panic(r)
+		}
+	}
+
+	db := s.clone()
+	for _, result := range results {
+		if result != nil {
+			db.AddError(result.Error)
+		}
+	}
+	return db
+}
+
+// fork return a DB for a worker, recording the statements it executes into a plan of its own if a plan is being recorded,
+// and the function merging them into that plan once the worker is done
+func (s *DB) fork() (*DB, func()) {
+	plan, ok := s.db.(*planDB)
+	if !ok {
+		return s.clone(), func() {}
+	}
+
+	clone := *plan
+	clone.plan = &Plan{parent: plan.plan}
+	return s.withDB(&clone), func() {
+		plan.plan.merge(clone.plan)
+	}
+}
@@ -0,0 +1,102 @@
+package automigrate_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+type parCompany struct {
+	ID   uint
+	Name string `automigrate:"index"`
+}
+
+type parUser struct {
+	ID        uint
+	Name      string `automigrate:"index"`
+	Email     string `automigrate:"unique_index"`
+	CompanyID uint
+	Company   parCompany `automigrate:"constraint"`
+}
+
+type parPost struct {
+	ID     uint
+	Title  string `automigrate:"index"`
+	UserID uint
+	User   parUser `automigrate:"constraint"`
+}
+
+type parTag struct {
+	ID   uint
+	Name string `automigrate:"unique_index"`
+}
+
+// parBroken and parBrokenToo have a type the database rejects, their migration fails
+type parBroken struct {
+	ID   uint
+	Name string `automigrate:"type:varchar(10"`
+}
+
+type parBrokenToo struct {
+	ID   uint
+	Name string `automigrate:"type:varchar(10"`
+}
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		values  []interface{}
+		want    []string
+		errors  int
+	}{
+		{name: "one worker", workers: 1, values: []interface{}{&parPost{}, &parUser{}, &parCompany{}, &parTag{}}, want: []string{"par_company", "par_post", "par_tag", "par_user"}},
+		{name: "workers", workers: 4, values: []interface{}{&parPost{}, &parUser{}, &parCompany{}, &parTag{}}, want: []string{"par_company", "par_post", "par_tag", "par_user"}},
+		{name: "more workers than models", workers: 16, values: []interface{}{&parTag{}, &parCompany{}}, want: []string{"par_company", "par_tag"}},
+		{name: "errors collected", workers: 4, values: []interface{}{&parBroken{}, &parPost{}, &parUser{}, &parCompany{}, &parBrokenToo{}, &parTag{}}, want: []string{"par_company", "par_post", "par_tag", "par_user"}, errors: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			adb = adb.Set("automigrate:workers", tt.workers)
+
+			// dry running doesn't execute the statements the database rejects
+			plan, err := adb.PlanMigrate(tt.values...)
+			if err != nil {
+				t.Fatalf("PlanMigrate error = %v", err)
+			}
+			// the steps of a table are kept together, after the ones of the tables it references
+			seen := map[string]bool{}
+			var order []string
+			for i, step := range plan.Steps {
+				if i > 0 && plan.Steps[i-1].Table == step.Table {
+					continue
+				}
+				if seen[step.Table] {
+					t.Errorf("steps of %v mixed with other tables' ones:\n%v", step.Table, plan)
+				}
+				seen[step.Table] = true
+				order = append(order, step.Table)
+			}
+			position := map[string]int{}
+			for i, tableName := range order {
+				position[tableName] = i
+			}
+			for _, dep := range [][2]string{{"par_user", "par_company"}, {"par_post", "par_user"}} {
+				if i, ok := position[dep[0]]; ok && i < position[dep[1]] {
+					t.Errorf("%v planned before %v, it references", dep[0], dep[1])
+				}
+			}
+			if got := tables(t, db); len(got) != 0 {
+				t.Errorf("tables after PlanMigrate = %v, want none", got)
+			}
+
+			result := adb.AutoMigrate(tt.values...)
+			if got := len(result.GetErrors()); got != tt.errors {
+				t.Errorf("AutoMigrate errors = %v, want %v", result.GetErrors(), tt.errors)
+			}
+			if got := tables(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package automigrate
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/database/gdb"
+)
+
+// PlanStep is a statement of a migration plan
+type PlanStep struct {
+	Table  string        // table changed by the statement
+	Kind   string        // kind of change, like create_table, add_column or add_index
+	Name   string        // name of the column, index or constraint changed, if any
+	Reason string        // why the change is needed
+	SQL    string        // statement to execute
+	Vars   []interface{} // values of the statement's placeholders
+}
+
+// Plan contains the statements of a migration, in execution order
+type Plan struct {
+	Steps []*PlanStep
+
+	l      sync.Mutex
+	parent *Plan // plan the steps are merged into, when recorded by a worker migrating a table
+}
+
+func (p *Plan) add(step *PlanStep) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.Steps = append(p.Steps, step)
+}
+
+// merge append the steps of the other plan
+func (p *Plan) merge(other *Plan) {
+	other.l.Lock()
+	steps := other.Steps
+	other.l.Unlock()
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.Steps = append(p.Steps, steps...)
+}
+
+// has check the plan has a step of the kind for the table and name or not
+func (p *Plan) has(kind, tableName, name string) bool {
+	p.l.Lock()
+	defer p.l.Unlock()
+	for _, step := range p.Steps {
+		if step.Kind == kind && step.Table == tableName && step.Name == name {
+			return true
+		}
+	}
+	return p.parent != nil && p.parent.has(kind, tableName, name)
+}
+
+// String return the plan as a SQL script, each statement preceded by a comment telling why it is needed
+func (p *Plan) String() string {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	var lines []string
+	for _, step := range p.Steps {
+		lines = append(lines, fmt.Sprintf("-- %v %v %v: %v", step.Table, step.Kind, step.Name, step.Reason))
+		if len(step.Vars) > 0 {
+			lines = append(lines, fmt.Sprintf("-- vars: %v", step.Vars))
+		}
+		lines = append(lines, step.SQL+";")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// planDB records every statement executed through it into the plan, and skips executing them when dry running
+type planDB struct {
+	gdb.DB
+	plan   *Plan
+	dryRun bool
+	step   PlanStep
+}
+
+// Exec record the statement in the plan, then execute it unless dry running
+func (db *planDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	step := db.step
+	step.SQL, step.Vars = query, args
+	db.plan.add(&step)
+
+	if db.dryRun {
+		return driver.RowsAffected(0), nil
+	}
+	return db.DB.Exec(query, args...)
+}
+
+// GetDryRun return true when dry running, so that dialects can skip work that doesn't go through Exec
+func (db *planDB) GetDryRun() bool {
+	return db.dryRun || db.DB.GetDryRun()
+}
+
+// PlanMigrate introspect the database like AutoMigrate, but collect the statements it would execute into a plan instead of executing them
+func (s *DB) PlanMigrate(values ...interface{}) (*Plan, error) {
+	plan := &Plan{}
+	db := s.withPlan(plan, true).AutoMigrate(values...)
+	return plan, db.Error
+}
+
+// withPlan return a new DB recording the statements it executes into plan, without executing them if dryRun is true
+func (s *DB) withPlan(plan *Plan, dryRun bool) *DB {
+	return s.withDB(&planDB{DB: s.db, plan: plan, dryRun: dryRun})
+}
+
+// describe set what the statements the scope executes next do, for the plan being recorded
+func (scope *Scope) describe(tableName, kind, name, reason string) *Scope {
+	if db, ok := scope.db.db.(*planDB); ok {
+		db.step = PlanStep{Table: tableName, Kind: kind, Name: name, Reason: reason}
+	}
+	return scope
+}
+
+// planned check the table is created or renamed by the plan being dry run, so it can't be introspected yet
+func (scope *Scope) planned(tableName string) bool {
+	db, ok := scope.db.db.(*planDB)
+	return ok && db.dryRun && (db.plan.has("create_table", tableName, "") || db.plan.has("rename_table", tableName, ""))
+}
@@ -0,0 +1,180 @@
+package automigrate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pruneTarget is a column, index or table missing from the models
+type pruneTarget struct {
+	kind      string // column, index or table
+	tableName string
+	name      string
+}
+
+func (target pruneTarget) String() string {
+	if target.kind == "table" {
+		return fmt.Sprintf("table %v", target.tableName)
+	}
+	return fmt.Sprintf("%v %v of table %v", target.kind, target.name, target.tableName)
+}
+
+// prune drop the columns, the indexes created by autoIndex and the tables missing from the models,
+// listing all of them before dropping any
+func (s *DB) prune(values ...interface{}) *DB {
+	var (
+		targets []pruneTarget
+		// the migrations history and lock tables have no model but must be kept
+		tables = map[string]bool{unqualifiedTableName(SchemaMigration{}.TableName()): true, unqualifiedTableName(migrationLock{}.TableName()): true}
+	)
+
+	for _, value := range values {
+		scope := s.NewScope(value)
+		tables[unqualifiedTableName(scope.TableName())] = true
+		// the previous tables are still there when dry running
+		if renamed, ok := value.(renamedFrom); ok {
+			for _, tableName := range renamed.PreviousTableNames() {
+				tables[unqualifiedTableName(tableName)] = true
+			}
+		}
+		for _, field := range scope.GetModelStruct().StructFields {
+			if relationship := field.Relationship; relationship != nil && relationship.JoinTableHandler != nil {
+				tables[unqualifiedTableName(relationship.JoinTableHandler.Table(s))] = true
+			}
+		}
+
+		targets = append(targets, scope.staleIndexes()...)
+		targets = append(targets, scope.staleColumns()...)
+	}
+	targets = append(targets, s.NewScope(nil).staleTables(tables)...)
+
+	if len(targets) == 0 {
+		return s
+	}
+
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.String())
+	}
+	defaultLogger.Print("warning", fmt.Sprintf("[warning] pruning %v objects missing from the models: %v", len(targets), strings.Join(names, ", ")))
+
+	db := s
+	for _, target := range targets {
+		scope := db.Table(target.tableName).NewScope(nil)
+		switch target.kind {
+		case "index":
+			scope.dropIndex(target.name, "index missing from the model")
+		case "column":
+			scope.dropColumn(target.name, "column missing from the model")
+		case "table":
+			scope.dropTable("table missing from the models")
+		}
+		db = scope.db
+	}
+	return db
+}
+
+// staleColumns return the columns of the table no field of the model maps to
+func (scope *Scope) staleColumns() (targets []pruneTarget) {
+	tableName := scope.TableName()
+	if scope.planned(tableName) {
+		return
+	}
+
+	fields := map[string]bool{}
+	for _, field := range scope.GetModelStruct().StructFields {
+		if field.IsNormal {
+			fields[strings.ToLower(field.DBName)] = true
+			// a column being renamed is not missing, it still exists when dry running
+			if names, ok := field.TagSettingsGet("RENAME_FROM"); ok {
+				for _, name := range strings.Split(names, ",") {
+					fields[strings.ToLower(strings.TrimSpace(name))] = true
+				}
+			}
+		}
+	}
+
+	for name, column := range scope.columns(tableName) {
+		if !fields[name] {
+			targets = append(targets, pruneTarget{kind: "column", tableName: tableName, name: column.Name})
+		}
+	}
+	return
+}
+
+// staleIndexes return the indexes of the table named like autoIndex names them that the model doesn't declare
+func (scope *Scope) staleIndexes() (targets []pruneTarget) {
+	tableName := scope.TableName()
+	dialect, ok := scope.Dialect().(catalogDialect)
+	if !ok || scope.planned(tableName) {
+		return
+	}
+
+	existing, err := dialect.Indexes(tableName)
+	if scope.Err(err) != nil {
+		return
+	}
+
+	declared := map[string]bool{}
+	indexes, uniqueIndexes := scope.modelIndexes()
+	for _, names := range []map[string][]string{indexes, uniqueIndexes} {
+		for name := range names {
+			declared[strings.ToLower(name)] = true
+		}
+	}
+
+	for _, index := range existing {
+		name := strings.ToLower(index.Name)
+		if (strings.HasPrefix(name, "idx_") || strings.HasPrefix(name, "uix_")) && !declared[name] {
+			targets = append(targets, pruneTarget{kind: "index", tableName: tableName, name: index.Name})
+		}
+	}
+	return
+}
+
+// staleTables return the tables of the database missing from the given ones
+func (scope *Scope) staleTables(tables map[string]bool) (targets []pruneTarget) {
+	dialect, ok := scope.Dialect().(catalogDialect)
+	if !ok {
+		return
+	}
+
+	existing, err := dialect.Tables()
+	if scope.Err(err) != nil {
+		return
+	}
+
+	for _, tableName := range existing {
+		if !tables[unqualifiedTableName(tableName)] {
+			targets = append(targets, pruneTarget{kind: "table", tableName: tableName})
+		}
+	}
+	return
+}
+
+// unqualifiedTableName return the lower cased table name without its database and schema,
+// so that tables named alike in another schema are never taken for missing ones
+func unqualifiedTableName(tableName string) string {
+	return strings.ToLower(tableName[strings.LastIndex(tableName, ".")+1:])
+}
+
+func (scope *Scope) dropColumn(columnName, reason string) {
+	scope.describe(scope.TableName(), "drop_column", columnName, reason)
+	if dialect, ok := scope.Dialect().(columnDropper); ok {
+		for _, sql := range dialect.DropColumnSQL(scope.TableName(), columnName) {
+			scope.Raw(sql).Exec()
+		}
+		return
+	}
+	scope.Raw(fmt.Sprintf("ALTER TABLE %v DROP COLUMN %v", scope.QuotedTableName(), scope.Quote(columnName))).Exec()
+}
+
+func (scope *Scope) dropIndex(indexName, reason string) {
+	scope.describe(scope.TableName(), "drop_index", indexName, reason)
+	scope.Err(scope.Dialect().RemoveIndex(scope.TableName(), indexName))
+}
+
+func (scope *Scope) dropTable(reason string) {
+	scope.describe(scope.TableName(), "drop_table", "", reason)
+	scope.Raw(fmt.Sprintf("DROP TABLE %v", scope.QuotedTableName())).Exec()
+}
@@ -0,0 +1,66 @@
+package automigrate_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+type pruneTeam struct {
+	ID   uint
+	Name string
+}
+
+type pruneMember struct {
+	ID    uint
+	Name  string
+	Teams []pruneTeam `automigrate:"many2many:prune_member_teams"`
+}
+
+type pruneStale struct {
+	ID uint
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   []string
+	}{
+		{
+			name:   "many to many",
+			values: []interface{}{&pruneMember{}, &pruneTeam{}},
+			want:   []string{"prune_member", "prune_member_teams", "prune_team"},
+		},
+		{
+			name:   "join table declared by a model only",
+			values: []interface{}{&pruneTeam{}, &pruneMember{}},
+			want:   []string{"prune_member", "prune_member_teams", "prune_team"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			if err := adb.AutoMigrate(&pruneStale{}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			if err := adb.Set("automigrate:prune", true).AutoMigrate(tt.values...).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got := tables(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables = %v, want %v", got, tt.want)
+			}
+			if got, want := columns(t, db, "prune_member_teams"), []string{"prune_member_id", "prune_team_id"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("join table columns = %v, want %v", got, want)
+			}
+
+			// migrating again finds nothing to change
+			if err := adb.Set("automigrate:prune", true).AutoMigrate(tt.values...).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got := tables(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables after migrating again = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -28,6 +28,14 @@ type dbTabler interface {
 	TableName(*DB) string
 }
 
+type tableCommenter interface {
+	TableComment() string
+}
+
+type renamedFrom interface {
+	PreviousTableNames() []string
+}
+
 // NewDB create a new DB without search information
 func (scope *Scope) NewDB() *DB {
 	if scope.db != nil {
@@ -46,7 +54,8 @@ func (scope *Scope) New(value interface{}) *Scope {
 
 // Exec execute raw sql
 func (s *DB) Exec(sql string, values ...interface{}) *DB {
-	scope := s.NewScope(nil)
+	// the statement isn't part of the migration step described last, when recorded into a plan
+	scope := s.NewScope(nil).describe("", "", "", "")
 	generatedSQL := scope.buildCondition(map[string]interface{}{"query": sql, "args": values}, true)
 	generatedSQL = strings.TrimSuffix(strings.TrimPrefix(generatedSQL, "("), ")")
 	scope.Raw(generatedSQL)
@@ -170,24 +179,238 @@ func (scope *Scope) autoMigrate() *Scope {
 	tableName := scope.TableName()
 	quotedTableName := scope.QuotedTableName()
 
-	if !scope.Dialect().HasTable(tableName) {
+	if scope.planned(tableName) {
+		return scope
+	}
+
+	if !scope.Dialect().HasTable(tableName) && !scope.renameTable() {
 		scope.createTable()
-	} else {
+	} else if !scope.planned(tableName) {
+		columns := scope.snapshot(tableName).columns
 		for _, field := range scope.GetModelStruct().StructFields {
-			if !scope.Dialect().HasColumn(tableName, field.DBName) {
-				if field.IsNormal {
-					sqlTag := scope.Dialect().DataTypeOf(field)
-					scope.Raw(fmt.Sprintf("ALTER TABLE %v ADD %v %v;", quotedTableName, scope.Quote(field.DBName), sqlTag)).Exec()
-				}
+			if !field.IsNormal {
+				scope.createJoinTable(field)
+				continue
+			}
+			if !scope.hasColumn(tableName, field.DBName) && !scope.renameColumn(field, columns) {
+				sqlTag := scope.Dialect().DataTypeOf(field)
+				scope.describe(tableName, "add_column", field.DBName, "column missing in table")
+				scope.Raw(fmt.Sprintf("ALTER TABLE %v ADD %v %v", quotedTableName, scope.Quote(field.DBName), sqlTag)).Exec()
+			} else if column, ok := columns[strings.ToLower(field.DBName)]; ok {
+				scope.alterColumn(field, column)
 			}
-			scope.createJoinTable(field)
 		}
 		scope.autoIndex()
+		scope.autoCheck()
+		scope.autoComment()
 	}
 	return scope
 }
 
+// columns return the existing columns of the table by lower cased name, or nil if the dialect can't read them
+func (scope *Scope) columns(tableName string) map[string]*ColumnInfo {
+	dialect, ok := scope.Dialect().(columnDialect)
+	if !ok {
+		return nil
+	}
+
+	columns, err := dialect.Columns(tableName)
+	if scope.Err(err) != nil {
+		return nil
+	}
+
+	columnsByName := map[string]*ColumnInfo{}
+	for _, column := range columns {
+		columnsByName[strings.ToLower(column.Name)] = column
+	}
+	return columnsByName
+}
+
+// renameTable rename the first existing table of PreviousTableNames to the table name,
+// along with the indexes autoIndex named after it
+func (scope *Scope) renameTable() bool {
+	renamed, ok := scope.Value.(renamedFrom)
+	if !ok {
+		return false
+	}
+
+	tableName := scope.TableName()
+	for _, oldName := range renamed.PreviousTableNames() {
+		if oldName == tableName || !scope.Dialect().HasTable(oldName) {
+			continue
+		}
+
+		// the indexes are looked for before the table is renamed, as dry runs leave it under its old name
+		var indexes [][2]string
+		for _, field := range scope.GetStructFields() {
+			for _, index := range [][2]string{{"idx", "INDEX"}, {"uix", "UNIQUE_INDEX"}} {
+				names, ok := field.TagSettingsGet(index[1])
+				if !ok {
+					continue
+				}
+				for _, name := range strings.Split(names, ",") {
+					if name != index[1] && name != "" {
+						continue
+					}
+					if oldIndexName := scope.Dialect().BuildKeyName(index[0], oldName, field.DBName); scope.Dialect().HasIndex(oldName, oldIndexName) {
+						indexes = append(indexes, [2]string{oldIndexName, scope.Dialect().BuildKeyName(index[0], tableName, field.DBName)})
+					}
+				}
+			}
+		}
+
+		scope.describe(tableName, "rename_table", "", fmt.Sprintf("table renamed from %v", oldName))
+		if dialect, ok := scope.Dialect().(tableRenamer); ok {
+			scope.Raw(dialect.RenameTableSQL(oldName, tableName)).Exec()
+		} else {
+			scope.Raw(fmt.Sprintf("ALTER TABLE %v RENAME TO %v", scope.Quote(oldName), scope.Dialect().Quote(tableName[strings.LastIndex(tableName, ".")+1:]))).Exec()
+		}
+
+		for _, index := range indexes {
+			dialect, ok := scope.Dialect().(tableRenamer)
+			if !ok {
+				// autoIndex creates it again under its new name
+				scope.dropIndex(index[0], fmt.Sprintf("index of table %v renamed", oldName))
+				continue
+			}
+			scope.describe(tableName, "rename_index", index[1], fmt.Sprintf("index renamed from %v", index[0]))
+			for _, sql := range dialect.RenameIndexSQL(tableName, index[0], index[1]) {
+				scope.Raw(sql).Exec()
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// renameColumn rename the column named by the RENAME_FROM tag to the field's column, if it exists, keeping its data
+func (scope *Scope) renameColumn(field *StructField, columns map[string]*ColumnInfo) bool {
+	names, ok := field.TagSettingsGet("RENAME_FROM")
+	if !ok || !field.IsNormal {
+		return false
+	}
+
+	tableName := scope.TableName()
+	for _, oldName := range strings.Split(names, ",") {
+		oldName = strings.TrimSpace(oldName)
+		if oldName == "" || !scope.hasColumn(tableName, oldName) {
+			continue
+		}
+
+		scope.describe(tableName, "rename_column", field.DBName, fmt.Sprintf("column renamed from %v", oldName))
+		if dialect, ok := scope.Dialect().(columnRenamer); ok {
+			scope.Raw(dialect.RenameColumnSQL(tableName, oldName, field.DBName)).Exec()
+		} else {
+			scope.Raw(fmt.Sprintf("ALTER TABLE %v RENAME COLUMN %v TO %v", scope.QuotedTableName(), scope.Quote(oldName), scope.Quote(field.DBName))).Exec()
+		}
+
+		if column, ok := columns[strings.ToLower(oldName)]; ok {
+			delete(columns, strings.ToLower(oldName))
+			column.Name = field.DBName
+			columns[strings.ToLower(field.DBName)] = column
+		}
+		return true
+	}
+	return false
+}
+
+// alterColumn change the type, the nullability and the default of the existing column when they differ from the field's,
+// type changes that may lose data are refused unless `automigrate:allow_narrowing` is set
+func (scope *Scope) alterColumn(field *StructField, column *ColumnInfo) {
+	var (
+		tableName                             = scope.TableName()
+		from                                  = parseDataType(column.Type)
+		to                                    = parseDataType(scope.Dialect().DataTypeOf(field))
+		typ                                   = column.Type
+		typeReason, nullReason, defaultReason string
+	)
+
+	if !from.equal(to) {
+		if from.canWiden(to) {
+			typeReason = fmt.Sprintf("type widened from %v", column.Type)
+		} else if allow, ok := scope.Get("automigrate:allow_narrowing"); ok && allow == true {
+			typeReason = fmt.Sprintf("type narrowed from %v", column.Type)
+		} else {
+			scope.Err(fmt.Errorf("%w: column %v of table %v from %v to %v, set automigrate:allow_narrowing to apply it", ErrNarrowingColumn, field.DBName, tableName, column.Type, to.sql))
+			return
+		}
+		typ = to.sql
+	}
+
+	// primary keys are never null
+	_, notNull := field.TagSettingsGet("NOT NULL")
+	if !field.IsPrimaryKey && column.Nullable == notNull {
+		if notNull {
+			nullReason = "column became not null"
+		} else {
+			nullReason = "column became nullable"
+		}
+	}
+
+	// the default of auto increment columns is their sequence
+	_, autoIncrement := field.TagSettingsGet("AUTO_INCREMENT")
+	defaultValue, hasDefault := field.TagSettingsGet("DEFAULT")
+	if !autoIncrement {
+		switch {
+		case hasDefault && !column.Default.Valid:
+			defaultReason = "default added"
+		case !hasDefault && column.Default.Valid:
+			defaultReason = fmt.Sprintf("default %v dropped", column.Default.String)
+		case hasDefault && !equalDefault(defaultValue, column.Default.String):
+			defaultReason = fmt.Sprintf("default changed from %v", column.Default.String)
+		}
+	}
+
+	alterer, ok := scope.Dialect().(columnAlterer)
+	if !ok {
+		// the whole column is redefined, nullability and default included
+		if reasons := joinNonBlank(typeReason, nullReason, defaultReason); reasons != "" {
+			scope.describe(tableName, "modify_column", field.DBName, reasons)
+			scope.Err(scope.Dialect().ModifyColumn(scope.QuotedTableName(), scope.Quote(field.DBName), scope.Dialect().(columnDialect).ModifyColumnType(field, column, typ)))
+		}
+		return
+	}
+
+	if typeReason != "" {
+		scope.describe(tableName, "modify_column", field.DBName, typeReason)
+		scope.Err(scope.Dialect().ModifyColumn(scope.QuotedTableName(), scope.Quote(field.DBName), scope.Dialect().(columnDialect).ModifyColumnType(field, column, typ)))
+	}
+
+	if nullReason != "" {
+		scope.describe(tableName, "modify_null", field.DBName, nullReason)
+		for _, sql := range alterer.SetNullSQL(tableName, field.DBName, typ, !notNull) {
+			scope.Raw(sql).Exec()
+		}
+	}
+
+	if defaultReason != "" {
+		scope.describe(tableName, "modify_default", field.DBName, defaultReason)
+		for _, sql := range alterer.SetDefaultSQL(tableName, field.DBName, defaultValue) {
+			scope.Raw(sql).Exec()
+		}
+	}
+}
+
 func (scope *Scope) autoIndex() *Scope {
+	indexes, uniqueIndexes := scope.modelIndexes()
+
+	for name, columns := range indexes {
+		if db := scope.NewDB().Table(scope.TableName()).Model(scope.Value).AddIndex(name, columns...); db.Error != nil {
+			scope.db.AddError(db.Error)
+		}
+	}
+
+	for name, columns := range uniqueIndexes {
+		if db := scope.NewDB().Table(scope.TableName()).Model(scope.Value).AddUniqueIndex(name, columns...); db.Error != nil {
+			scope.db.AddError(db.Error)
+		}
+	}
+
+	return scope
+}
+
+// modelIndexes return the columns of the indexes and unique indexes declared by the INDEX and UNIQUE_INDEX tags, by index name
+func (scope *Scope) modelIndexes() (map[string][]string, map[string][]string) {
 	var indexes = map[string][]string{}
 	var uniqueIndexes = map[string][]string{}
 
@@ -217,21 +440,91 @@ func (scope *Scope) autoIndex() *Scope {
 		}
 	}
 
-	for name, columns := range indexes {
-		if db := scope.NewDB().Table(scope.TableName()).Model(scope.Value).AddIndex(name, columns...); db.Error != nil {
-			scope.db.AddError(db.Error)
-		}
+	return indexes, uniqueIndexes
+}
+
+// autoForeignKey create the foreign key of the relationship field tagged with CONSTRAINT, as in `automigrate:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`,
+// belongs to relationships constrain the model's table, has one and has many ones the associated table
+func (scope *Scope) autoForeignKey(field *StructField) *Scope {
+	constraint, ok := field.TagSettingsGet("CONSTRAINT")
+	relationship := field.Relationship
+	if !ok || relationship == nil || relationship.PolymorphicType != "" || !scope.canAddForeignKey() {
+		return scope
 	}
 
-	for name, columns := range uniqueIndexes {
-		if db := scope.NewDB().Table(scope.TableName()).Model(scope.Value).AddUniqueIndex(name, columns...); db.Error != nil {
-			scope.db.AddError(db.Error)
+	var (
+		toScope             = scope.New(reflect.New(field.Struct.Type).Interface())
+		tableName, destName string
+	)
+	switch relationship.Kind {
+	case "belongs_to":
+		tableName, destName = scope.TableName(), toScope.TableName()
+	case "has_one", "has_many":
+		tableName, destName = toScope.TableName(), scope.TableName()
+	default:
+		return scope
+	}
+
+	var references []string
+	for _, name := range relationship.AssociationForeignDBNames {
+		references = append(references, scope.Quote(name))
+	}
+	dest := fmt.Sprintf("%v(%v)", scope.Quote(destName), strings.Join(references, ", "))
+	onDelete, onUpdate := constraintActions(constraint)
+	if db := scope.NewDB().Table(tableName).AddForeignKey(strings.Join(relationship.ForeignDBNames, ","), dest, onDelete, onUpdate); db.Error != nil {
+		scope.db.AddError(db.Error)
+	}
+	return scope
+}
+
+// constraintActions return the ON DELETE and ON UPDATE actions of the CONSTRAINT tag, blank if not given
+func constraintActions(constraint string) (onDelete, onUpdate string) {
+	for _, option := range strings.Split(constraint, ",") {
+		if kv := strings.SplitN(option, ":", 2); len(kv) == 2 {
+			switch strings.ToUpper(strings.TrimSpace(kv[0])) {
+			case "ONDELETE":
+				onDelete = strings.ToUpper(strings.TrimSpace(kv[1]))
+			case "ONUPDATE":
+				onUpdate = strings.ToUpper(strings.TrimSpace(kv[1]))
+			}
 		}
 	}
+	return
+}
+
+// autoComment keep the comments of the table and its columns in line with the model, for dialects storing comments apart
+func (scope *Scope) autoComment() *Scope {
+	if _, ok := scope.Dialect().(commentDialect); !ok {
+		return scope
+	}
+
+	if commenter, ok := scope.Value.(tableCommenter); ok {
+		scope.setComment("", commenter.TableComment())
+	}
 
+	for _, field := range scope.GetModelStruct().StructFields {
+		if comment, ok := field.TagSettingsGet("COMMENT"); ok && field.IsNormal {
+			scope.setComment(field.DBName, unquoteComment(comment))
+		}
+	}
 	return scope
 }
 
+// setComment add or update the comment of the table, or of its column if columnName isn't blank
+func (scope *Scope) setComment(columnName, comment string) {
+	dialect := scope.Dialect().(commentDialect)
+	current, exists := dialect.GetComment(scope.TableName(), columnName)
+	if exists && current == comment {
+		return
+	}
+	reason := "comment missing"
+	if exists {
+		reason = fmt.Sprintf("comment changed from %q", current)
+	}
+	scope.describe(scope.TableName(), "comment", columnName, reason)
+	scope.Raw(dialect.SetCommentSQL(scope.TableName(), columnName, comment, exists)).Exec()
+}
+
 // Set set value by name
 func (scope *Scope) Set(name string, value interface{}) *Scope {
 	scope.db.InstantSet(name, value)
@@ -319,8 +612,12 @@ func (scope *Scope) quoteIfPossible(str string) string {
 }
 
 func (scope *Scope) addIndex(unique bool, indexName string, column ...string) {
-	if scope.Dialect().HasIndex(scope.TableName(), indexName) {
-		return
+	reason := "index missing in table"
+	if scope.hasIndex(scope.TableName(), indexName) {
+		if reason = scope.indexDrift(unique, indexName, column); reason == "" {
+			return
+		}
+		scope.dropIndex(indexName, reason)
 	}
 
 	var columns []string
@@ -333,5 +630,100 @@ func (scope *Scope) addIndex(unique bool, indexName string, column ...string) {
 		sqlCreate = "CREATE UNIQUE INDEX"
 	}
 
+	scope.describe(scope.TableName(), "add_index", indexName, reason)
 	scope.Raw(fmt.Sprintf("%s %v ON %v(%v) %v", sqlCreate, indexName, scope.QuotedTableName(), strings.Join(columns, ", "), scope.whereSQL())).Exec()
 }
+
+// addForeignKey add the foreign key on the comma separated columns of field referencing dest, as in cities(id),
+// named with BuildKeyName unless it already exists. Blank actions are left to the database default.
+func (scope *Scope) addForeignKey(field string, dest string, onDelete string, onUpdate string) {
+	if !scope.canAddForeignKey() {
+		scope.Err(fmt.Errorf("%v can't add foreign keys to the existing table %v", scope.Dialect().GetName(), scope.TableName()))
+		return
+	}
+
+	keyName := strings.TrimRight(scope.Dialect().BuildKeyName("fk", scope.TableName(), field, dest), "_")
+	if scope.hasForeignKey(scope.TableName(), keyName) {
+		return
+	}
+
+	var columns []string
+	for _, name := range strings.Split(field, ",") {
+		columns = append(columns, scope.quoteIfPossible(strings.TrimSpace(name)))
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %v ADD CONSTRAINT %v FOREIGN KEY (%v) REFERENCES %v", scope.QuotedTableName(), scope.quoteIfPossible(keyName), strings.Join(columns, ", "), dest)
+	if onDelete != "" {
+		query += " ON DELETE " + onDelete
+	}
+	if onUpdate != "" {
+		query += " ON UPDATE " + onUpdate
+	}
+	scope.describe(scope.TableName(), "add_foreign_key", keyName, "foreign key missing in table")
+	scope.Raw(query).Exec()
+}
+
+// canAddForeignKey check the dialect can add foreign keys to existing tables
+func (scope *Scope) canAddForeignKey() bool {
+	dialect, ok := scope.Dialect().(foreignKeyAdder)
+	return !ok || dialect.CanAddForeignKey()
+}
+
+// dropForeignKeySQL return the statement dropping the foreign key of the table
+func (scope *Scope) dropForeignKeySQL(tableName, foreignKeyName string) string {
+	if dialect, ok := scope.Dialect().(foreignKeyDropper); ok {
+		return dialect.DropForeignKeySQL(tableName, foreignKeyName)
+	}
+	return fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v", scope.Quote(tableName), scope.quoteIfPossible(foreignKeyName))
+}
+
+// dropCheckSQL return the statement dropping the check constraint of the table
+func (scope *Scope) dropCheckSQL(tableName, checkName string) string {
+	if dialect, ok := scope.Dialect().(checkDropper); ok {
+		return dialect.DropCheckSQL(tableName, checkName)
+	}
+	return fmt.Sprintf("ALTER TABLE %v DROP CONSTRAINT %v", scope.Quote(tableName), scope.quoteIfPossible(checkName))
+}
+
+// indexDrift return how the existing index differs from the one addIndex would create, blank if it doesn't
+func (scope *Scope) indexDrift(unique bool, indexName string, columns []string) string {
+	index := scope.existingIndex(indexName)
+	if index == nil {
+		return ""
+	}
+
+	var columnsReason, uniqueReason, filterReason string
+	if !equalIndexColumns(index.Columns, columns) {
+		columnsReason = fmt.Sprintf("columns changed from (%v) to (%v)", strings.Join(index.Columns, ", "), strings.Join(columns, ", "))
+	}
+	if index.Unique != unique {
+		uniqueReason = fmt.Sprintf("unique changed from %v to %v", index.Unique, unique)
+	}
+	if filter := scope.whereSQL(); normalizeCondition(index.Filter) != normalizeCondition(filter) {
+		filterReason = fmt.Sprintf("filter changed from %q to %q", index.Filter, strings.TrimSpace(filter))
+	}
+	return joinNonBlank(columnsReason, uniqueReason, filterReason)
+}
+
+// existingIndex return the definition of the existing index from the snapshot of the table, or else from the catalog, nil if it can't be read
+func (scope *Scope) existingIndex(indexName string) *IndexInfo {
+	if snapshot := scope.tableSnapshot(scope.TableName()); snapshot != nil && snapshot.indexes != nil {
+		return snapshot.indexes[strings.ToLower(indexName)]
+	}
+
+	dialect, ok := scope.Dialect().(catalogDialect)
+	if !ok {
+		return nil
+	}
+
+	indexes, err := dialect.Indexes(scope.TableName())
+	if scope.Err(err) != nil {
+		return nil
+	}
+	for _, index := range indexes {
+		if strings.EqualFold(index.Name, indexName) {
+			return index
+		}
+	}
+	return nil
+}
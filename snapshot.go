@@ -0,0 +1,117 @@
+package automigrate
+
+import (
+	"strings"
+	"sync"
+)
+
+// tableSnapshot is the definition of an existing table read when migrating it, with one query per kind of object,
+// so that checking its columns, indexes and constraints one by one doesn't query the catalog each time.
+// The objects are keyed by lower cased name, a kind is nil if the dialect can't list it.
+type tableSnapshot struct {
+	columns     map[string]*ColumnInfo
+	indexes     map[string]*IndexInfo
+	checks      map[string]string
+	foreignKeys map[string]bool
+}
+
+// snapshots contains the snapshots of the tables a migration read, by table name, shared by the DBs running it
+type snapshots struct {
+	l      sync.Mutex
+	tables map[string]*tableSnapshot
+}
+
+// withSnapshots return a new DB keeping the snapshots of the tables it migrates, until withoutSnapshots
+func (s *DB) withSnapshots() *DB {
+	return s.Set("automigrate:snapshots", &snapshots{tables: map[string]*tableSnapshot{}})
+}
+
+// withoutSnapshots forget the snapshots, that later changes of the tables would make stale
+func (s *DB) withoutSnapshots() *DB {
+	s.values.Delete("automigrate:snapshots")
+	return s
+}
+
+// snapshot read the columns, indexes and constraints of the table, and keep them for the rest of the migration if it keeps snapshots
+func (scope *Scope) snapshot(tableName string) *tableSnapshot {
+	snapshot := &tableSnapshot{columns: scope.columns(tableName)}
+
+	if dialect, ok := scope.Dialect().(catalogDialect); ok {
+		if indexes, err := dialect.Indexes(tableName); scope.Err(err) == nil {
+			snapshot.indexes = map[string]*IndexInfo{}
+			for _, index := range indexes {
+				snapshot.indexes[strings.ToLower(index.Name)] = index
+			}
+		}
+	}
+
+	// checks are read only for the models declaring some, as not every database version can read them
+	if dialect, ok := scope.Dialect().(checkDialect); ok && len(scope.modelChecks()) > 0 {
+		if checks, err := dialect.Checks(tableName); scope.Err(err) == nil && checks != nil {
+			snapshot.checks = map[string]string{}
+			for name, expression := range checks {
+				snapshot.checks[strings.ToLower(name)] = expression
+			}
+		}
+	}
+
+	if dialect, ok := scope.Dialect().(foreignKeyDialect); ok {
+		if foreignKeys, err := dialect.ForeignKeys(tableName); scope.Err(err) == nil {
+			snapshot.foreignKeys = map[string]bool{}
+			for _, name := range foreignKeys {
+				snapshot.foreignKeys[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	if value, ok := scope.Get("automigrate:snapshots"); ok {
+		snapshots := value.(*snapshots)
+		snapshots.l.Lock()
+		snapshots.tables[tableName] = snapshot
+		snapshots.l.Unlock()
+	}
+	return snapshot
+}
+
+// tableSnapshot return the snapshot of the table read by the migration, nil if there's none
+func (scope *Scope) tableSnapshot(tableName string) *tableSnapshot {
+	value, ok := scope.Get("automigrate:snapshots")
+	if !ok {
+		return nil
+	}
+
+	snapshots := value.(*snapshots)
+	snapshots.l.Lock()
+	defer snapshots.l.Unlock()
+	return snapshots.tables[tableName]
+}
+
+// hasColumn check the table has the column. Columns missing from the snapshot are looked for in the database,
+// as they may have been added since it was read, or be spelled with another case.
+func (scope *Scope) hasColumn(tableName, columnName string) bool {
+	if snapshot := scope.tableSnapshot(tableName); snapshot != nil {
+		if _, ok := snapshot.columns[strings.ToLower(columnName)]; ok {
+			return true
+		}
+	}
+	return scope.Dialect().HasColumn(tableName, columnName)
+}
+
+// hasIndex check the table has the index, looking for it in the database if it's missing from the snapshot,
+// which leaves out the indexes backing constraints
+func (scope *Scope) hasIndex(tableName, indexName string) bool {
+	if snapshot := scope.tableSnapshot(tableName); snapshot != nil {
+		if _, ok := snapshot.indexes[strings.ToLower(indexName)]; ok {
+			return true
+		}
+	}
+	return scope.Dialect().HasIndex(tableName, indexName)
+}
+
+// hasForeignKey check the table has the foreign key, looking for it in the database if it's missing from the snapshot
+func (scope *Scope) hasForeignKey(tableName, foreignKeyName string) bool {
+	if snapshot := scope.tableSnapshot(tableName); snapshot != nil && snapshot.foreignKeys[strings.ToLower(foreignKeyName)] {
+		return true
+	}
+	return scope.Dialect().HasForeignKey(tableName, foreignKeyName)
+}
@@ -0,0 +1,220 @@
+package automigrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/gogf/gf/database/gdb"
+)
+
+// linkDB runs the statements and queries executed through it with the context of the migration, in the transaction if any,
+// so that introspection sees the changes not committed yet and doesn't wait for their locks
+type linkDB struct {
+	gdb.DB
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+var (
+	countRegexp  = regexp.MustCompile(`(?i)SELECT\s+COUNT\(.+\)\s+FROM`)
+	selectRegexp = regexp.MustCompile(`(?i)(SELECT)\s+(.+)\s+(FROM)`)
+)
+
+// link return the transaction, or else the master connection pool, running statements with the context
+func (db *linkDB) link() (gdb.Link, error) {
+	if db.tx != nil {
+		return &ctxLink{link: db.tx, ctx: db.ctx}, nil
+	}
+	master, err := db.DB.Master()
+	if err != nil {
+		return nil, err
+	}
+	return &ctxLink{link: master, ctx: db.ctx}, nil
+}
+
+func (db *linkDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	link, err := db.link()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.DB.DoExec(link, query, args...)
+	return result, db.interrupted(err, query)
+}
+
+func (db *linkDB) GetAll(query string, args ...interface{}) (gdb.Result, error) {
+	link, err := db.link()
+	if err != nil {
+		return nil, err
+	}
+	result, err := db.DB.DoGetAll(link, query, args...)
+	return result, db.interrupted(err, query)
+}
+
+func (db *linkDB) GetOne(query string, args ...interface{}) (gdb.Record, error) {
+	result, err := db.GetAll(query, args...)
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+func (db *linkDB) GetValue(query string, args ...interface{}) (gdb.Value, error) {
+	record, err := db.GetOne(query, args...)
+	for _, value := range record {
+		return value, err
+	}
+	return nil, err
+}
+
+func (db *linkDB) GetArray(query string, args ...interface{}) ([]gdb.Value, error) {
+	result, err := db.GetAll(query, args...)
+	return result.Array(), err
+}
+
+// GetCount count the rows the query selects, like gdb does
+func (db *linkDB) GetCount(query string, args ...interface{}) (int, error) {
+	if !countRegexp.MatchString(query) {
+		query = selectRegexp.ReplaceAllString(query, "$1 COUNT($2) $3")
+	}
+	value, err := db.GetValue(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return value.Int(), nil
+}
+
+func (db *linkDB) GetStructs(objPointerSlice interface{}, query string, args ...interface{}) error {
+	result, err := db.GetAll(query, args...)
+	if err != nil {
+		return err
+	}
+	return result.Structs(objPointerSlice)
+}
+
+// interrupted report the statement the context interrupted, if it did
+func (db *linkDB) interrupted(err error, query string) error {
+	if err != nil && db.ctx.Err() != nil {
+		return fmt.Errorf("%w, interrupted statement: %v", db.ctx.Err(), query)
+	}
+	return err
+}
+
+// withLink return db running its statements with the context and in the transaction if not nil, below the plan recording them if any
+func withLink(db gdb.DB, ctx context.Context, tx *sql.Tx) gdb.DB {
+	switch db := db.(type) {
+	case *planDB:
+		clone := *db
+		clone.DB = withLink(db.DB, ctx, tx)
+		return &clone
+	case *linkDB:
+		return &linkDB{DB: db.DB, ctx: ctx, tx: tx}
+	}
+	return &linkDB{DB: db, ctx: ctx, tx: tx}
+}
+
+// transactionOf return the transaction the statements executed through db run in, nil if there's none
+func transactionOf(db gdb.DB) *sql.Tx {
+	switch db := db.(type) {
+	case *linkDB:
+		return db.tx
+	case *planDB:
+		return transactionOf(db.DB)
+	}
+	return nil
+}
+
+// inTransaction check the statements executed through db already run in a transaction
+func inTransaction(db gdb.DB) bool {
+	return transactionOf(db) != nil
+}
+
+// withTransaction return db running its statements in the transaction, below the plan recording them if any
+func withTransaction(db gdb.DB, tx *sql.Tx) gdb.DB {
+	return withLink(db, contextOf(db), tx)
+}
+
+// transactional check fn has to run in a transaction: the dialect's DDL must be transactional, and there must be no transaction yet
+func (s *DB) transactional() bool {
+	dialect, ok := s.dialect.(transactionalDialect)
+	return ok && dialect.TransactionalDDL() && !s.db.GetDryRun() && !inTransaction(s.db)
+}
+
+// transaction run fn in a transaction if the dialect's DDL is transactional, committing it unless fn added errors to the DB,
+// and rolling back everything fn did otherwise. Other dialects run fn's statements one by one.
+func (s *DB) transaction(fn func(*DB) *DB) (db *DB) {
+	if !s.transactional() {
+		return fn(s)
+	}
+
+	master, err := s.db.Master()
+	var tx *sql.Tx
+	if err == nil {
+		tx, err = master.BeginTx(contextOf(s.db), nil)
+	}
+	if err != nil {
+		db = s.clone()
+		db.AddError(err)
+		return db
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	errs := len(s.GetErrors())
+	db = fn(s.withDB(withTransaction(s.db, tx)))
+	if len(db.GetErrors()) > errs {
+		// a canceled context already rolled the transaction back
+		if err := tx.Rollback(); err != sql.ErrTxDone {
+			db.AddError(err)
+		}
+	} else {
+		db.AddError(tx.Commit())
+	}
+	return db.withDB(s.db)
+}
+
+// Transaction run fn in a transaction, with a gdb.DB executing its statements through the plan and with the context db has,
+// so that dialects running several statements at once record them and are interrupted like the others.
+// fn joins the transaction db runs in if any, and runs without one when dry running.
+func Transaction(db gdb.DB, fn func(db gdb.DB) error) error {
+	if db.GetDryRun() || inTransaction(db) {
+		return fn(db)
+	}
+
+	master, err := db.Master()
+	if err != nil {
+		return err
+	}
+	tx, err := master.BeginTx(contextOf(db), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(withTransaction(db, tx)); err != nil {
+		// a canceled context already rolled the transaction back
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && rollbackErr != sql.ErrTxDone {
+			return fmt.Errorf("%w, rolling back: %v", err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// withDB return a new DB executing its statements through db, with a dialect of its own using it
+func (s *DB) withDB(db gdb.DB) *DB {
+	clone := s.clone()
+	clone.db = db
+	clone.dialect = newDialect(s.dialect.GetName(), db, s.catalog)
+	return clone
+}
@@ -0,0 +1,53 @@
+package automigrate_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+type txFirst struct {
+	ID uint
+}
+
+type txSecond struct {
+	ID   uint
+	Name string
+}
+
+// txBroken has a type the database rejects, its migration fails
+type txBroken struct {
+	ID   uint
+	Name string `automigrate:"type:varchar(10"`
+}
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    interface{}
+		values  []interface{}
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", values: []interface{}{&txFirst{}, &txBroken{}, &txSecond{}}, want: []string{"tx_first", "tx_second"}, wantErr: true},
+		{name: "model", mode: "model", values: []interface{}{&txFirst{}, &txBroken{}, &txSecond{}}, want: []string{"tx_first", "tx_second"}, wantErr: true},
+		{name: "model as true", mode: true, values: []interface{}{&txFirst{}, &txBroken{}}, want: []string{"tx_first"}, wantErr: true},
+		{name: "batch", mode: "batch", values: []interface{}{&txFirst{}, &txBroken{}, &txSecond{}}, wantErr: true},
+		{name: "batch succeeding", mode: "batch", values: []interface{}{&txFirst{}, &txSecond{}}, want: []string{"tx_first", "tx_second"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adb, db := openSQLite(t)
+			if tt.mode != nil {
+				adb = adb.Set("automigrate:transaction", tt.mode)
+			}
+
+			err := adb.AutoMigrate(tt.values...).Error
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AutoMigrate error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := tables(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
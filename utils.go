@@ -224,3 +224,13 @@ func addExtraSpaceIfExist(str string) string {
 	}
 	return ""
 }
+
+func joinNonBlank(strs ...string) string {
+	var nonBlank []string
+	for _, str := range strs {
+		if str != "" {
+			nonBlank = append(nonBlank, str)
+		}
+	}
+	return strings.Join(nonBlank, ", ")
+}